@@ -2,7 +2,9 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/Leakageonthelamp/go-leakage-core/utils"
@@ -11,24 +13,51 @@ import (
 
 type ICache interface {
 	Set(key string, value interface{}, expiration time.Duration) error
+	SetNX(key string, value interface{}, expiration time.Duration) (bool, error)
 	SetJSON(key string, value interface{}, expiration time.Duration) error
 	Get(dest interface{}, key string) error
 	GetJSON(dest interface{}, key string) error
 	Del(key string) error
+	DelPattern(pattern string) (int64, error)
+	Incr(key string) (int64, error)
+	IncrBy(key string, value int64) (int64, error)
+	Decr(key string) (int64, error)
+	Expire(key string, expiration time.Duration) (bool, error)
+	TTL(key string) (time.Duration, error)
+	Exists(keys ...string) (int64, error)
+	MGet(keys ...string) ([]interface{}, error)
+	MSet(values map[string]interface{}, expiration time.Duration) error
+	HSet(key string, values map[string]interface{}) error
+	HGet(dest interface{}, key string, field string) error
+	HGetAll(key string) (map[string]string, error)
+	SAdd(key string, members ...interface{}) error
+	SMembers(key string) ([]string, error)
+	Lock(key string, options *CacheLockOptions) (ICacheLock, error)
+	TryLock(key string, wait time.Duration, options *CacheLockOptions) (ICacheLock, error)
 	Close()
+	WithContext(ctx context.Context) ICache
+	Ping() error
 }
 
+// cacheScanCount is the number of keys of a SCAN batch of DelPattern
+const cacheScanCount = 100
+
 type DatabaseCache struct {
 	Host string
 	Port string
 }
 
+var cacheError = Error{
+	Status:  http.StatusInternalServerError,
+	Code:    "CACHE_ERROR",
+	Message: "cache internal error",
+}
+
 type cache struct {
 	rdb *redis.Client
+	ctx context.Context
 }
 
-var ctx = context.Background()
-
 func NewCache(env *ENVConfig) *DatabaseCache {
 	return &DatabaseCache{
 		Host: env.CacheHost,
@@ -41,12 +70,35 @@ func (r DatabaseCache) Connect() (ICache, error) {
 		Addr: fmt.Sprintf("%s:%s", r.Host, r.Port),
 	})
 
-	status := rdb.Ping(ctx)
+	status := rdb.Ping(context.Background())
 	if status.Err() != nil {
 		return nil, status.Err()
 	}
 
-	return &cache{rdb}, nil
+	return &cache{rdb: rdb, ctx: context.Background()}, nil
+}
+
+// WithContext return a copy of the cache whose commands are bound to ctx
+func (c cache) WithContext(ctx context.Context) ICache {
+	c.ctx = ctx
+	return &c
+}
+
+// client return the underlying redis client, it is used by features that need raw redis commands e.g. the rate limiter
+func (c cache) client() *redis.Client {
+	return c.rdb
+}
+
+func (c cache) getContext() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+
+	return c.ctx
+}
+
+func (c cache) Ping() error {
+	return c.rdb.Ping(c.getContext()).Err()
 }
 
 func (c cache) Close() {
@@ -57,15 +109,32 @@ func (c cache) Close() {
 }
 
 func (c cache) Set(key string, value interface{}, expiration time.Duration) error {
-	return c.rdb.Set(ctx, key, value, expiration).Err()
+	return c.rdb.Set(c.getContext(), key, value, expiration).Err()
+}
+
+// SetNX set key only when it does not exist, it returns false when the key already exists
+func (c cache) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	return c.rdb.SetNX(c.getContext(), key, value, expiration).Result()
 }
 
 func (c cache) Get(dest interface{}, key string) error {
-	return c.rdb.Get(ctx, key).Scan(dest)
+	err := c.rdb.Get(c.getContext(), key).Scan(dest)
+	if metrics := MetricsFromContext(c.ctx); metrics != nil {
+		switch {
+		case err == nil:
+			metrics.ObserveCache("get", CacheResultHit)
+		case errors.Is(err, redis.Nil):
+			metrics.ObserveCache("get", CacheResultMiss)
+		default:
+			metrics.ObserveCache("get", CacheResultError)
+		}
+	}
+
+	return err
 }
 
 func (c cache) Del(key string) error {
-	return c.rdb.Del(ctx, key).Err()
+	return c.rdb.Del(c.getContext(), key).Err()
 }
 
 func (c cache) SetJSON(key string, value interface{}, expiration time.Duration) error {
@@ -82,3 +151,103 @@ func (c cache) GetJSON(dest interface{}, key string) error {
 
 	return utils.JSONParse(utils.StringToBytes(str), dest)
 }
+
+// DelPattern delete the keys that match pattern, e.g. user:*, the keys are scanned in batches so redis is not blocked.
+// It returns the number of deleted keys
+func (c cache) DelPattern(pattern string) (int64, error) {
+	ctx := c.getContext()
+	var deleted int64
+	var cursor uint64
+	for {
+		keys, next, err := c.rdb.Scan(ctx, cursor, pattern, cacheScanCount).Result()
+		if err != nil {
+			return deleted, err
+		}
+
+		if len(keys) > 0 {
+			n, err := c.rdb.Del(ctx, keys...).Result()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return deleted, nil
+		}
+	}
+}
+
+// Incr increment the counter of key by one, the key is set to 0 first when it does not exist
+func (c cache) Incr(key string) (int64, error) {
+	return c.rdb.Incr(c.getContext(), key).Result()
+}
+
+func (c cache) IncrBy(key string, value int64) (int64, error) {
+	return c.rdb.IncrBy(c.getContext(), key, value).Result()
+}
+
+func (c cache) Decr(key string) (int64, error) {
+	return c.rdb.Decr(c.getContext(), key).Result()
+}
+
+// Expire set the expiration of key, it returns false when the key does not exist
+func (c cache) Expire(key string, expiration time.Duration) (bool, error) {
+	return c.rdb.Expire(c.getContext(), key, expiration).Result()
+}
+
+// TTL return the remaining time of key, it's -1ns when the key has no expiration and -2ns when the key does not exist
+func (c cache) TTL(key string) (time.Duration, error) {
+	return c.rdb.TTL(c.getContext(), key).Result()
+}
+
+// Exists return the number of keys that exist
+func (c cache) Exists(keys ...string) (int64, error) {
+	return c.rdb.Exists(c.getContext(), keys...).Result()
+}
+
+// MGet return the values of keys in the same order, the value of a missing key is nil
+func (c cache) MGet(keys ...string) ([]interface{}, error) {
+	return c.rdb.MGet(c.getContext(), keys...).Result()
+}
+
+// MSet set every key of values, they are set with the expiration in one transaction when expiration is not 0
+func (c cache) MSet(values map[string]interface{}, expiration time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	if expiration == 0 {
+		return c.rdb.MSet(c.getContext(), values).Err()
+	}
+
+	_, err := c.rdb.TxPipelined(c.getContext(), func(pipe redis.Pipeliner) error {
+		for key, value := range values {
+			pipe.Set(c.getContext(), key, value, expiration)
+		}
+		return nil
+	})
+
+	return err
+}
+
+func (c cache) HSet(key string, values map[string]interface{}) error {
+	return c.rdb.HSet(c.getContext(), key, values).Err()
+}
+
+func (c cache) HGet(dest interface{}, key string, field string) error {
+	return c.rdb.HGet(c.getContext(), key, field).Scan(dest)
+}
+
+func (c cache) HGetAll(key string) (map[string]string, error) {
+	return c.rdb.HGetAll(c.getContext(), key).Result()
+}
+
+func (c cache) SAdd(key string, members ...interface{}) error {
+	return c.rdb.SAdd(c.getContext(), key, members...).Err()
+}
+
+func (c cache) SMembers(key string) ([]string, error) {
+	return c.rdb.SMembers(c.getContext(), key).Result()
+}
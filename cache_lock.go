@@ -0,0 +1,244 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Leakageonthelamp/go-leakage-core/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultCacheLockTTL        = 30 * time.Second
+	defaultCacheLockRetryDelay = 100 * time.Millisecond
+)
+
+var (
+	// ErrCacheLockNotAcquired is returned when the lock is held by another holder
+	ErrCacheLockNotAcquired = errors.New("cache lock is not acquired")
+	// ErrCacheLockNotHeld is returned when the lock is expired or taken by another holder
+	ErrCacheLockNotHeld = errors.New("cache lock is not held")
+)
+
+// the lock is only extended or deleted by the holder of its token
+var (
+	cacheLockExtendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+	cacheLockReleaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+)
+
+type CacheLockOptions struct {
+	TTL        time.Duration // time the lock is held when it's not extended, default is 30s
+	AutoExtend bool          // the lock is extended every TTL/3 until it's released
+	RetryDelay time.Duration // delay between the attempts of TryLock, default is 100ms
+}
+
+type ICacheLock interface {
+	// Key return the redis key of the lock
+	Key() string
+	// Token return the random token of the holder
+	Token() string
+	// Extend reset the TTL of the lock, it returns ErrCacheLockNotHeld when the lock is lost
+	Extend(ttl time.Duration) error
+	// Release delete the lock, it returns ErrCacheLockNotHeld when the lock is lost
+	Release() error
+	// ReleaseAfter stop the auto extension and keep the lock for d, e.g. so the work is not done again right after
+	ReleaseAfter(d time.Duration) error
+	// Lost is closed when the auto extension finds the lock is expired or taken by another holder
+	Lost() <-chan struct{}
+}
+
+type cacheLock struct {
+	rdb      *redis.Client
+	ctx      context.Context
+	key      string
+	token    string
+	ttl      time.Duration
+	mu       sync.Mutex
+	stop     chan struct{}
+	stopOnce sync.Once
+	lost     chan struct{}
+}
+
+// Lock acquire the lock of key with a random token, it returns ErrCacheLockNotAcquired when the lock is held by another holder.
+// The lock is kept in a single redis, so it's not safe against a failover of redis
+func (c cache) Lock(key string, options *CacheLockOptions) (ICacheLock, error) {
+	if options == nil {
+		options = &CacheLockOptions{}
+	}
+
+	ttl := options.TTL
+	if ttl <= 0 {
+		ttl = defaultCacheLockTTL
+	}
+
+	token := utils.GetUUID()
+	ok, err := c.rdb.SetNX(c.getContext(), cacheLockKey(key), token, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, ErrCacheLockNotAcquired
+	}
+
+	// the lock is extended and released after the context of the cache is done
+	lock := &cacheLock{
+		rdb:   c.rdb,
+		ctx:   context.WithoutCancel(c.getContext()),
+		key:   cacheLockKey(key),
+		token: token,
+		ttl:   ttl,
+		stop:  make(chan struct{}),
+		lost:  make(chan struct{}),
+	}
+
+	if options.AutoExtend {
+		go lock.autoExtend()
+	}
+
+	return lock, nil
+}
+
+// TryLock acquire the lock of key like Lock, it tries again until wait is passed or the context of the cache is done
+func (c cache) TryLock(key string, wait time.Duration, options *CacheLockOptions) (ICacheLock, error) {
+	if options == nil {
+		options = &CacheLockOptions{}
+	}
+
+	retryDelay := options.RetryDelay
+	if retryDelay <= 0 {
+		retryDelay = defaultCacheLockRetryDelay
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		lock, err := c.Lock(key, options)
+		if !errors.Is(err, ErrCacheLockNotAcquired) {
+			return lock, err
+		}
+
+		delay := retryDelay
+		if remaining := time.Until(deadline); remaining < delay {
+			delay = remaining
+		}
+		if delay <= 0 {
+			return nil, ErrCacheLockNotAcquired
+		}
+
+		select {
+		case <-c.getContext().Done():
+			return nil, c.getContext().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (l *cacheLock) Key() string {
+	return l.key
+}
+
+func (l *cacheLock) Token() string {
+	return l.token
+}
+
+func (l *cacheLock) Extend(ttl time.Duration) error {
+	n, err := cacheLockExtendScript.Run(l.ctx, l.rdb, []string{l.key}, l.token, ttl.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrCacheLockNotHeld
+	}
+
+	return nil
+}
+
+func (l *cacheLock) Release() error {
+	l.stopAutoExtend()
+	n, err := cacheLockReleaseScript.Run(l.ctx, l.rdb, []string{l.key}, l.token).Int()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrCacheLockNotHeld
+	}
+
+	return nil
+}
+
+func (l *cacheLock) ReleaseAfter(d time.Duration) error {
+	if d <= 0 {
+		return l.Release()
+	}
+
+	l.stopAutoExtend()
+	return l.Extend(d)
+}
+
+func (l *cacheLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// stopAutoExtend stop the auto extension, it waits for an extension in progress so it can't override a later TTL
+func (l *cacheLock) stopAutoExtend() {
+	l.stopOnce.Do(func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		close(l.stop)
+	})
+}
+
+// autoExtend extend the lock until it's released, a failed extension is tried again at the next tick until the lock is lost
+func (l *cacheLock) autoExtend() {
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			if !l.extendOnTick() {
+				return
+			}
+		}
+	}
+}
+
+// extendOnTick extend the lock unless it's stopped, it returns false once the extension should stop
+func (l *cacheLock) extendOnTick() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	select {
+	case <-l.stop:
+		return false
+	default:
+	}
+
+	if err := l.Extend(l.ttl); errors.Is(err, ErrCacheLockNotHeld) {
+		close(l.lost)
+		return false
+	}
+
+	return true
+}
+
+func cacheLockKey(key string) string {
+	return "lock:" + key
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheLock(t *testing.T) {
+	c, mr := newTestCache(t)
+
+	lock, err := c.Lock("job", &CacheLockOptions{TTL: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, "lock:job", lock.Key())
+	assert.Equal(t, time.Minute, mr.TTL("lock:job"))
+
+	_, err = c.Lock("job", nil)
+	assert.ErrorIs(t, err, ErrCacheLockNotAcquired)
+
+	require.NoError(t, lock.Extend(2*time.Minute))
+	assert.Equal(t, 2*time.Minute, mr.TTL("lock:job"))
+
+	require.NoError(t, lock.Release())
+	assert.False(t, mr.Exists("lock:job"))
+	assert.ErrorIs(t, lock.Release(), ErrCacheLockNotHeld)
+}
+
+func TestCacheLockReleaseChecksToken(t *testing.T) {
+	c, mr := newTestCache(t)
+
+	lock, err := c.Lock("job", nil)
+	require.NoError(t, err)
+
+	// the lock expired and was taken by another holder
+	require.NoError(t, mr.Set("lock:job", "other"))
+
+	assert.ErrorIs(t, lock.Extend(time.Minute), ErrCacheLockNotHeld)
+	assert.ErrorIs(t, lock.Release(), ErrCacheLockNotHeld)
+	value, err := mr.Get("lock:job")
+	require.NoError(t, err)
+	assert.Equal(t, "other", value)
+}
+
+func TestCacheLockReleaseAfter(t *testing.T) {
+	c, mr := newTestCache(t)
+
+	lock, err := c.Lock("job", &CacheLockOptions{TTL: time.Minute, AutoExtend: true})
+	require.NoError(t, err)
+
+	require.NoError(t, lock.ReleaseAfter(10*time.Second))
+	assert.Equal(t, 10*time.Second, mr.TTL("lock:job"))
+
+	require.NoError(t, lock.ReleaseAfter(0))
+	assert.False(t, mr.Exists("lock:job"))
+}
+
+func TestCacheLockAutoExtend(t *testing.T) {
+	c, mr := newTestCache(t)
+
+	lock, err := c.Lock("job", &CacheLockOptions{TTL: 150 * time.Millisecond, AutoExtend: true})
+	require.NoError(t, err)
+	defer lock.Release()
+
+	// the TTL of miniredis only goes down when the time is forwarded, so it's back to the full TTL once extended
+	mr.FastForward(100 * time.Millisecond)
+	require.Eventually(t, func() bool {
+		return mr.TTL("lock:job") == 150*time.Millisecond
+	}, time.Second, 10*time.Millisecond)
+
+	select {
+	case <-lock.Lost():
+		t.Fatal("the lock is lost")
+	default:
+	}
+}
+
+func TestCacheLockLost(t *testing.T) {
+	c, mr := newTestCache(t)
+
+	lock, err := c.Lock("job", &CacheLockOptions{TTL: 150 * time.Millisecond, AutoExtend: true})
+	require.NoError(t, err)
+
+	require.NoError(t, mr.Set("lock:job", "other"))
+
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("the lock is not lost")
+	}
+
+	assert.ErrorIs(t, lock.Release(), ErrCacheLockNotHeld)
+}
+
+func TestCacheTryLock(t *testing.T) {
+	c, _ := newTestCache(t)
+
+	lock, err := c.Lock("job", nil)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = c.TryLock("job", 200*time.Millisecond, &CacheLockOptions{RetryDelay: 50 * time.Millisecond})
+	assert.ErrorIs(t, err, ErrCacheLockNotAcquired)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	time.AfterFunc(100*time.Millisecond, func() {
+		_ = lock.Release()
+	})
+
+	next, err := c.TryLock("job", time.Second, &CacheLockOptions{RetryDelay: 20 * time.Millisecond})
+	require.NoError(t, err)
+	assert.NotEqual(t, lock.Token(), next.Token())
+}
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockCache is a mock of ICache interface, WithContext returns the mock itself
+type MockCache struct {
+	mock.Mock
+}
+
+func NewMockCache() *MockCache {
+	return &MockCache{}
+}
+
+func (m *MockCache) Set(key string, value interface{}, expiration time.Duration) error {
+	args := m.Called(key, value, expiration)
+	return args.Error(0)
+}
+
+func (m *MockCache) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	args := m.Called(key, value, expiration)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCache) SetJSON(key string, value interface{}, expiration time.Duration) error {
+	args := m.Called(key, value, expiration)
+	return args.Error(0)
+}
+
+func (m *MockCache) Get(dest interface{}, key string) error {
+	args := m.Called(dest, key)
+	return args.Error(0)
+}
+
+func (m *MockCache) GetJSON(dest interface{}, key string) error {
+	args := m.Called(dest, key)
+	return args.Error(0)
+}
+
+func (m *MockCache) Del(key string) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockCache) DelPattern(pattern string) (int64, error) {
+	args := m.Called(pattern)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCache) Incr(key string) (int64, error) {
+	args := m.Called(key)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCache) IncrBy(key string, value int64) (int64, error) {
+	args := m.Called(key, value)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCache) Decr(key string) (int64, error) {
+	args := m.Called(key)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCache) Expire(key string, expiration time.Duration) (bool, error) {
+	args := m.Called(key, expiration)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCache) TTL(key string) (time.Duration, error) {
+	args := m.Called(key)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+func (m *MockCache) Exists(keys ...string) (int64, error) {
+	args := m.Called(keys)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCache) MGet(keys ...string) ([]interface{}, error) {
+	args := m.Called(keys)
+	return mockCacheValue[[]interface{}](args, 0), args.Error(1)
+}
+
+func (m *MockCache) MSet(values map[string]interface{}, expiration time.Duration) error {
+	args := m.Called(values, expiration)
+	return args.Error(0)
+}
+
+func (m *MockCache) HSet(key string, values map[string]interface{}) error {
+	args := m.Called(key, values)
+	return args.Error(0)
+}
+
+func (m *MockCache) HGet(dest interface{}, key string, field string) error {
+	args := m.Called(dest, key, field)
+	return args.Error(0)
+}
+
+func (m *MockCache) HGetAll(key string) (map[string]string, error) {
+	args := m.Called(key)
+	return mockCacheValue[map[string]string](args, 0), args.Error(1)
+}
+
+func (m *MockCache) SAdd(key string, members ...interface{}) error {
+	args := m.Called(key, members)
+	return args.Error(0)
+}
+
+func (m *MockCache) SMembers(key string) ([]string, error) {
+	args := m.Called(key)
+	return mockCacheValue[[]string](args, 0), args.Error(1)
+}
+
+func (m *MockCache) Lock(key string, options *CacheLockOptions) (ICacheLock, error) {
+	args := m.Called(key, options)
+	return mockCacheValue[ICacheLock](args, 0), args.Error(1)
+}
+
+func (m *MockCache) TryLock(key string, wait time.Duration, options *CacheLockOptions) (ICacheLock, error) {
+	args := m.Called(key, wait, options)
+	return mockCacheValue[ICacheLock](args, 0), args.Error(1)
+}
+
+func (m *MockCache) Close() {
+	m.Called()
+}
+
+func (m *MockCache) WithContext(_ context.Context) ICache {
+	return m
+}
+
+func (m *MockCache) Ping() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+// MockCacheLock is a mock of ICacheLock interface
+type MockCacheLock struct {
+	mock.Mock
+}
+
+func NewMockCacheLock() *MockCacheLock {
+	return &MockCacheLock{}
+}
+
+func (m *MockCacheLock) Key() string {
+	args := m.Called()
+	return args.String(0)
+}
+
+func (m *MockCacheLock) Token() string {
+	args := m.Called()
+	return args.String(0)
+}
+
+func (m *MockCacheLock) Extend(ttl time.Duration) error {
+	args := m.Called(ttl)
+	return args.Error(0)
+}
+
+func (m *MockCacheLock) Release() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockCacheLock) ReleaseAfter(d time.Duration) error {
+	args := m.Called(d)
+	return args.Error(0)
+}
+
+func (m *MockCacheLock) Lost() <-chan struct{} {
+	args := m.Called()
+	return mockCacheValue[<-chan struct{}](args, 0)
+}
+
+// mockCacheValue return the argument at index, nil is returned as the zero value of T
+func mockCacheValue[T any](args mock.Arguments, index int) T {
+	var value T
+	if obj := args.Get(index); obj != nil {
+		value = obj.(T)
+	}
+
+	return value
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// rateLimitScript implement GCRA (generic cell rate algorithm), the theoretical arrival time (TAT) of the key is kept in
+// microseconds of the redis server clock so every instance share the same clock
+var rateLimitScript = redis.NewScript(`
+if redis.replicate_commands then
+	redis.replicate_commands()
+end
+
+local burst = tonumber(ARGV[1])
+local emission = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local tolerance = emission * burst
+
+local tat = tonumber(redis.call("GET", KEYS[1]))
+if not tat or tat < now then
+	tat = now
+end
+
+local new_tat = tat + emission
+local allow_at = new_tat - tolerance
+if allow_at > now then
+	local remaining = math.floor((now - (tat - tolerance)) / emission)
+	return {0, remaining, allow_at - now, tat - now}
+end
+
+redis.call("SET", KEYS[1], new_tat, "PX", math.max(1, math.ceil((new_tat - now) / 1000)))
+return {1, math.floor((now - allow_at) / emission), 0, new_tat - now}
+`)
+
+// ErrRateLimiterStoreNotSupported is returned when the cache is not backed by redis
+var ErrRateLimiterStoreNotSupported = errors.New("rate limiter store needs a redis cache")
+
+type RateLimit struct {
+	Rate   int           // number of requests allowed per Period
+	Period time.Duration // period of Rate
+	Burst  int           // number of requests allowed at once, Rate is used when zero
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // time to wait until the next request is allowed, zero when allowed
+	ResetAfter time.Duration // time until the limit is fully reset
+}
+
+type IRateLimiterStore interface {
+	Allow(ctx context.Context, key string, limit RateLimit) (*RateLimitResult, error)
+}
+
+type redisClient interface {
+	client() *redis.Client
+}
+
+type rateLimiterStore struct {
+	rdb *redis.Client
+}
+
+// NewRateLimiterStore return a rate limiter store backed by the redis of cache, the limit is shared between all instances
+func NewRateLimiterStore(cache ICache) (IRateLimiterStore, error) {
+	c, ok := cache.(redisClient)
+	if !ok {
+		return nil, ErrRateLimiterStoreNotSupported
+	}
+
+	return &rateLimiterStore{rdb: c.client()}, nil
+}
+
+// Allow take one request from the limit of key
+func (s rateLimiterStore) Allow(ctx context.Context, key string, limit RateLimit) (*RateLimitResult, error) {
+	burst := limit.Burst
+	if burst <= 0 {
+		burst = limit.Rate
+	}
+
+	if limit.Rate <= 0 || limit.Period <= 0 || burst <= 0 {
+		return nil, errors.New("rate limit must have positive rate, period and burst")
+	}
+
+	emission := limit.Period.Microseconds() / int64(limit.Rate)
+	if emission <= 0 {
+		emission = 1
+	}
+
+	values, err := rateLimitScript.Run(ctx, s.rdb, []string{key}, burst, emission).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) != 4 {
+		return nil, errors.New("unexpected rate limit script result")
+	}
+
+	remaining := int(values[1])
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &RateLimitResult{
+		Allowed:    values[0] == 1,
+		Limit:      burst,
+		Remaining:  remaining,
+		RetryAfter: time.Duration(values[2]) * time.Microsecond,
+		ResetAfter: time.Duration(values[3]) * time.Microsecond,
+	}, nil
+}
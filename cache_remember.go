@@ -0,0 +1,308 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/Leakageonthelamp/go-leakage-core/utils"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	defaultRememberJitter     = 0.1
+	defaultRememberLockTTL    = 10 * time.Second
+	defaultRememberRetryDelay = 50 * time.Millisecond
+)
+
+// rememberGroup run the loader of a key once across the concurrent callers of the instance
+var rememberGroup singleflight.Group
+
+type RememberOptions struct {
+	StaleTTL    time.Duration // an expired value is returned for StaleTTL while it's reloaded in background, 0 disables it
+	NotFoundTTL time.Duration // a 404 error of the loader, e.g. errmsgs.NotFound, is cached for NotFoundTTL, 0 disables it
+	Jitter      float64       // the TTLs are randomized by ±Jitter so the keys don't expire at once, default is 0.1 and a negative value disables it
+	LockTTL     time.Duration // TTL of the lock that runs the loader on a single pod, default is 10s
+	LockWait    time.Duration // time a miss waits for the loader of another pod before it runs its own loader, default is LockTTL
+}
+
+// rememberEntry is the cached value of Remember, a cached not found error has no value
+type rememberEntry struct {
+	Value      json.RawMessage `json:"value,omitempty"`
+	Error      *rememberError  `json:"error,omitempty"`
+	FreshUntil time.Time       `json:"fresh_until"`
+}
+
+type rememberError struct {
+	Status  int         `json:"status"`
+	Code    string      `json:"code"`
+	Message interface{} `json:"message"`
+	Fields  interface{} `json:"fields,omitempty"`
+}
+
+// Remember return the value of key from ctx.Cache(), on a miss the loader runs once across the concurrent callers
+// of the instance and, with a lock key, across the pods, and its result is cached for ttl
+func Remember[T any](ctx IContext, key string, ttl time.Duration, loader func() (T, IError)) (T, IError) {
+	return RememberWithOptions(ctx, key, ttl, loader, nil)
+}
+
+// RememberWithOptions return the value of key like Remember with stale-while-revalidate and negative caching.
+// The loader is shared by the concurrent callers and the stale value is reloaded after the caller returns,
+// so the loader should not depend on the cancellation of ctx, each caller stops waiting when its own ctx is done
+func RememberWithOptions[T any](ctx IContext, key string, ttl time.Duration, loader func() (T, IError), options *RememberOptions) (T, IError) {
+	var value T
+	cache := ctx.Cache()
+	if cache == nil {
+		return loader()
+	}
+
+	r := newRemember(ctx, cache, key, ttl, options, func() (interface{}, IError) {
+		return loader()
+	})
+
+	entry, ierr := r.get()
+	if ierr != nil {
+		return value, ierr
+	}
+
+	if entry.Error != nil {
+		return value, entry.Error.toError()
+	}
+
+	if err := json.Unmarshal(entry.Value, &value); err != nil {
+		return value, ctx.NewError(err, cacheError)
+	}
+
+	return value, nil
+}
+
+type remember struct {
+	ctx     IContext
+	cache   ICache
+	key     string
+	ttl     time.Duration
+	options RememberOptions
+	loader  func() (interface{}, IError)
+}
+
+func newRemember(ctx IContext, cache ICache, key string, ttl time.Duration, options *RememberOptions, loader func() (interface{}, IError)) *remember {
+	r := &remember{ctx: ctx, cache: cache, key: key, ttl: ttl, loader: loader}
+	if options != nil {
+		r.options = *options
+	}
+	if r.options.Jitter == 0 {
+		r.options.Jitter = defaultRememberJitter
+	}
+	if r.options.LockTTL <= 0 {
+		r.options.LockTTL = defaultRememberLockTTL
+	}
+	if r.options.LockWait <= 0 {
+		r.options.LockWait = r.options.LockTTL
+	}
+
+	return r
+}
+
+// get return the cached entry, a stale entry is returned and reloaded in background
+func (r *remember) get() (*rememberEntry, IError) {
+	entry, found := r.read()
+	if found {
+		if !entry.isStale() {
+			return entry, nil
+		}
+
+		r.revalidate()
+		return entry, nil
+	}
+
+	// the load is shared by the callers, so it's not cancelled with the caller that starts it
+	shared := r.detach()
+	ch := rememberGroup.DoChan(r.key, func() (interface{}, error) {
+		entry, ierr := shared.loadWithLock()
+		if ierr != nil {
+			return nil, ierr
+		}
+		return entry, nil
+	})
+
+	select {
+	case <-r.ctx.GetContext().Done():
+		return nil, r.ctx.NewError(r.ctx.GetContext().Err(), cacheError)
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err.(IError)
+		}
+
+		return res.Val.(*rememberEntry), nil
+	}
+}
+
+// read return the cached entry, an error of the cache is logged and treated as a miss
+func (r *remember) read() (*rememberEntry, bool) {
+	entry := &rememberEntry{}
+	err := r.cache.GetJSON(entry, r.key)
+	if errors.Is(err, redis.Nil) {
+		return nil, false
+	}
+	if err != nil {
+		r.ctx.NewError(err, cacheError)
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// loadWithLock run the loader on the pod that takes the lock of the key, the other pods wait for its value up to LockWait
+func (r *remember) loadWithLock() (*rememberEntry, IError) {
+	deadline := time.Now().Add(r.options.LockWait)
+	for {
+		lock, err := r.cache.Lock(r.lockKey(), &CacheLockOptions{TTL: r.options.LockTTL, AutoExtend: true})
+		if err == nil {
+			defer r.release(lock)
+
+			// the value can be loaded by another pod between the miss and the lock
+			if entry, found := r.read(); found {
+				return entry, nil
+			}
+
+			return r.load()
+		}
+
+		if !errors.Is(err, ErrCacheLockNotAcquired) {
+			r.ctx.NewError(err, cacheError)
+			return r.load()
+		}
+
+		if time.Now().After(deadline) {
+			return r.load()
+		}
+
+		select {
+		case <-r.ctx.GetContext().Done():
+			return nil, r.ctx.NewError(r.ctx.GetContext().Err(), cacheError)
+		case <-time.After(defaultRememberRetryDelay):
+		}
+
+		if entry, found := r.read(); found {
+			return entry, nil
+		}
+	}
+}
+
+// load run the loader and cache its value or its not found error
+func (r *remember) load() (*rememberEntry, IError) {
+	value, ierr := r.callLoader()
+	if ierr != nil {
+		if ierr.GetStatus() != http.StatusNotFound || r.options.NotFoundTTL <= 0 {
+			return nil, ierr
+		}
+
+		entry := &rememberEntry{Error: &rememberError{
+			Status:  ierr.GetStatus(),
+			Code:    ierr.GetCode(),
+			Message: ierr.GetMessage(),
+			Fields:  getErrorFields(ierr),
+		}}
+		r.write(entry, r.jitter(r.options.NotFoundTTL), 0)
+		return entry, nil
+	}
+
+	entry := &rememberEntry{Value: json.RawMessage(utils.JSONToString(value))}
+	ttl := r.jitter(r.ttl)
+	r.write(entry, ttl, r.options.StaleTTL)
+	return entry, nil
+}
+
+// callLoader call the loader, a panic is returned as cacheError
+func (r *remember) callLoader() (value interface{}, ierr IError) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			ierr = r.ctx.NewError(fmt.Errorf("%v", rec), cacheError)
+		}
+	}()
+
+	return r.loader()
+}
+
+// write cache the entry, it's fresh for ttl and kept for staleTTL after that
+func (r *remember) write(entry *rememberEntry, ttl time.Duration, staleTTL time.Duration) {
+	expiration := time.Duration(0)
+	if ttl > 0 {
+		entry.FreshUntil = time.Now().Add(ttl)
+		expiration = ttl + staleTTL
+	}
+
+	if err := r.cache.SetJSON(r.key, entry, expiration); err != nil {
+		r.ctx.NewError(err, cacheError)
+	}
+}
+
+// detach return a copy of r whose context is not cancelled with the context of the caller
+func (r *remember) detach() *remember {
+	detached := &remember{
+		ctx:     r.ctx.WithContext(context.WithoutCancel(r.ctx.GetContext())),
+		key:     r.key,
+		ttl:     r.ttl,
+		options: r.options,
+		loader:  r.loader,
+	}
+	detached.cache = detached.ctx.Cache()
+
+	return detached
+}
+
+// revalidate reload the stale entry in background on the pod that takes the lock of the key
+func (r *remember) revalidate() {
+	bg := r.detach()
+	go rememberGroup.Do("revalidate:"+r.key, func() (interface{}, error) {
+		lock, err := bg.cache.Lock(bg.lockKey(), &CacheLockOptions{TTL: bg.options.LockTTL, AutoExtend: true})
+		if errors.Is(err, ErrCacheLockNotAcquired) {
+			return nil, nil
+		}
+		if err != nil {
+			bg.ctx.NewError(err, cacheError)
+			return nil, nil
+		}
+		defer bg.release(lock)
+
+		_, _ = bg.load()
+		return nil, nil
+	})
+}
+
+func (r *remember) release(lock ICacheLock) {
+	if err := lock.Release(); err != nil && !errors.Is(err, ErrCacheLockNotHeld) {
+		r.ctx.NewError(err, cacheError)
+	}
+}
+
+func (r *remember) lockKey() string {
+	return "remember:" + r.key
+}
+
+func (r *remember) jitter(ttl time.Duration) time.Duration {
+	if ttl <= 0 || r.options.Jitter <= 0 {
+		return ttl
+	}
+
+	return ttl + time.Duration((rand.Float64()*2-1)*r.options.Jitter*float64(ttl))
+}
+
+func (e *rememberEntry) isStale() bool {
+	return !e.FreshUntil.IsZero() && time.Now().After(e.FreshUntil)
+}
+
+func (e *rememberError) toError() IError {
+	return Error{
+		Status:        e.Status,
+		Code:          e.Code,
+		Message:       e.Message,
+		Fields:        e.Fields,
+		originalError: fmt.Errorf("%v", e.Message),
+	}
+}
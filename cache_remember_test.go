@@ -0,0 +1,235 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRememberTestContext(t *testing.T) (IContext, ICache) {
+	cache, _ := newTestCache(t)
+	return NewContext(&ContextOptions{ENV: NewEnv(), Cache: cache}), cache
+}
+
+func setRememberTestEntry(t *testing.T, cache ICache, key string, value interface{}, freshUntil time.Time) {
+	b, err := json.Marshal(value)
+	require.NoError(t, err)
+	require.NoError(t, cache.SetJSON(key, &rememberEntry{Value: b, FreshUntil: freshUntil}, 0))
+}
+
+func TestRemember(t *testing.T) {
+	ctx, cache := newRememberTestContext(t)
+
+	var calls int32
+	loader := func() (string, IError) {
+		atomic.AddInt32(&calls, 1)
+		return "john", nil
+	}
+
+	value, ierr := Remember(ctx, "user:1", time.Minute, loader)
+	require.NoError(t, ierr)
+	assert.Equal(t, "john", value)
+
+	value, ierr = Remember(ctx, "user:1", time.Minute, loader)
+	require.NoError(t, ierr)
+	assert.Equal(t, "john", value)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	ttl, err := cache.TTL("user:1")
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Minute)*defaultRememberJitter)
+}
+
+func TestRememberStampede(t *testing.T) {
+	ctx, _ := newRememberTestContext(t)
+
+	var calls int32
+	loader := func() (string, IError) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(100 * time.Millisecond)
+		return "john", nil
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, ierr := Remember(ctx, "user:1", time.Minute, loader)
+			assert.NoError(t, ierr)
+			assert.Equal(t, "john", value)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestRememberWaitsForTheLockHolder(t *testing.T) {
+	ctx, cache := newRememberTestContext(t)
+
+	// another pod is loading the value
+	lock, err := cache.Lock("remember:user:1", nil)
+	require.NoError(t, err)
+	time.AfterFunc(150*time.Millisecond, func() {
+		setRememberTestEntry(t, cache, "user:1", "from another pod", time.Now().Add(time.Minute))
+		_ = lock.Release()
+	})
+
+	var calls int32
+	value, ierr := RememberWithOptions(ctx, "user:1", time.Minute, func() (string, IError) {
+		atomic.AddInt32(&calls, 1)
+		return "john", nil
+	}, &RememberOptions{LockWait: time.Second})
+	require.NoError(t, ierr)
+	assert.Equal(t, "from another pod", value)
+	assert.Zero(t, atomic.LoadInt32(&calls))
+}
+
+func TestRememberLoadsAfterLockWait(t *testing.T) {
+	ctx, cache := newRememberTestContext(t)
+
+	lock, err := cache.Lock("remember:user:1", nil)
+	require.NoError(t, err)
+	defer lock.Release()
+
+	start := time.Now()
+	value, ierr := RememberWithOptions(ctx, "user:1", time.Minute, func() (string, IError) {
+		return "john", nil
+	}, &RememberOptions{LockWait: 100 * time.Millisecond})
+	require.NoError(t, ierr)
+	assert.Equal(t, "john", value)
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestRememberStaleWhileRevalidate(t *testing.T) {
+	ctx, cache := newRememberTestContext(t)
+	setRememberTestEntry(t, cache, "user:1", "old", time.Now().Add(-time.Second))
+
+	reloaded := make(chan struct{})
+	options := &RememberOptions{StaleTTL: time.Minute}
+	value, ierr := RememberWithOptions(ctx, "user:1", time.Minute, func() (string, IError) {
+		defer close(reloaded)
+		return "new", nil
+	}, options)
+	require.NoError(t, ierr)
+	assert.Equal(t, "old", value)
+
+	select {
+	case <-reloaded:
+	case <-time.After(time.Second):
+		t.Fatal("the stale value is not reloaded")
+	}
+
+	require.Eventually(t, func() bool {
+		value, ierr = RememberWithOptions(ctx, "user:1", time.Minute, func() (string, IError) {
+			return "unexpected", nil
+		}, options)
+		return ierr == nil && value == "new"
+	}, time.Second, 10*time.Millisecond)
+
+	ttl, err := cache.TTL("user:1")
+	require.NoError(t, err)
+	assert.Greater(t, ttl, time.Minute)
+}
+
+func TestRememberNotFound(t *testing.T) {
+	notFound := Error{Status: http.StatusNotFound, Code: "USER_NOT_FOUND", Message: "user is not found"}
+
+	tests := []struct {
+		name      string
+		options   *RememberOptions
+		wantCalls int32
+	}{
+		{name: "cached", options: &RememberOptions{NotFoundTTL: time.Minute}, wantCalls: 1},
+		{name: "not cached without NotFoundTTL", options: nil, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := newRememberTestContext(t)
+
+			var calls int32
+			for i := 0; i < 2; i++ {
+				_, ierr := RememberWithOptions(ctx, "user:1", time.Minute, func() (string, IError) {
+					atomic.AddInt32(&calls, 1)
+					return "", notFound
+				}, tt.options)
+				require.Error(t, ierr)
+				assert.Equal(t, http.StatusNotFound, ierr.GetStatus())
+				assert.Equal(t, "USER_NOT_FOUND", ierr.GetCode())
+			}
+
+			assert.Equal(t, tt.wantCalls, atomic.LoadInt32(&calls))
+		})
+	}
+}
+
+func TestRememberOtherErrorsAreNotCached(t *testing.T) {
+	ctx, cache := newRememberTestContext(t)
+
+	_, ierr := RememberWithOptions(ctx, "user:1", time.Minute, func() (string, IError) {
+		return "", Error{Status: http.StatusInternalServerError, Code: "INTERNAL_SERVER_ERROR"}
+	}, &RememberOptions{NotFoundTTL: time.Minute})
+	require.Error(t, ierr)
+
+	n, err := cache.Exists("user:1")
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestRememberCallerCancellation(t *testing.T) {
+	ctx, _ := newRememberTestContext(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loader := func() (string, IError) {
+		close(started)
+		<-release
+		return "john", nil
+	}
+
+	firstCtx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan IError)
+	go func() {
+		_, ierr := Remember(ctx.WithContext(firstCtx), "user:1", time.Minute, loader)
+		firstErr <- ierr
+	}()
+	<-started
+
+	secondValue := make(chan string)
+	go func() {
+		value, ierr := Remember(ctx, "user:1", time.Minute, func() (string, IError) {
+			return "unexpected", nil
+		})
+		assert.NoError(t, ierr)
+		secondValue <- value
+	}()
+
+	// the first caller gives up, the load it started goes on for the second caller
+	cancel()
+	assert.Error(t, <-firstErr)
+
+	close(release)
+	assert.Equal(t, "john", <-secondValue)
+}
+
+func TestRememberJitter(t *testing.T) {
+	r := newRemember(nil, nil, "key", time.Minute, nil, nil)
+	for i := 0; i < 100; i++ {
+		ttl := r.jitter(time.Minute)
+		assert.GreaterOrEqual(t, ttl, 54*time.Second)
+		assert.LessOrEqual(t, ttl, 66*time.Second)
+	}
+
+	r = newRemember(nil, nil, "key", time.Minute, &RememberOptions{Jitter: -1}, nil)
+	assert.Equal(t, time.Minute, r.jitter(time.Minute))
+	assert.Zero(t, r.jitter(0))
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestCache return a cache connected to a miniredis that is closed with the test
+func newTestCache(t *testing.T) (ICache, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	cache, err := DatabaseCache{Host: mr.Host(), Port: mr.Port()}.Connect()
+	require.NoError(t, err)
+	t.Cleanup(cache.Close)
+
+	return cache, mr
+}
+
+func TestCache(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, c ICache, mr *miniredis.Miniredis)
+	}{
+		{
+			name: "DelPattern deletes every matching key over many scan batches",
+			run: func(t *testing.T, c ICache, mr *miniredis.Miniredis) {
+				for i := 0; i < cacheScanCount*2+5; i++ {
+					require.NoError(t, mr.Set(fmt.Sprintf("user:%d", i), "1"))
+				}
+				require.NoError(t, mr.Set("post:1", "1"))
+
+				n, err := c.DelPattern("user:*")
+				require.NoError(t, err)
+				assert.Equal(t, int64(cacheScanCount*2+5), n)
+				assert.Equal(t, []string{"post:1"}, mr.Keys())
+
+				n, err = c.DelPattern("user:*")
+				require.NoError(t, err)
+				assert.Zero(t, n)
+			},
+		},
+		{
+			name: "MSet without expiration",
+			run: func(t *testing.T, c ICache, mr *miniredis.Miniredis) {
+				require.NoError(t, c.MSet(map[string]interface{}{"a": 1, "b": "two"}, 0))
+
+				values, err := c.MGet("a", "b", "missing")
+				require.NoError(t, err)
+				assert.Equal(t, []interface{}{"1", "two", nil}, values)
+				assert.Zero(t, mr.TTL("a"))
+			},
+		},
+		{
+			name: "MSet with expiration",
+			run: func(t *testing.T, c ICache, mr *miniredis.Miniredis) {
+				require.NoError(t, c.MSet(map[string]interface{}{"a": 1, "b": 2}, time.Minute))
+				assert.Equal(t, time.Minute, mr.TTL("a"))
+				assert.Equal(t, time.Minute, mr.TTL("b"))
+
+				mr.FastForward(time.Minute)
+				n, err := c.Exists("a", "b")
+				require.NoError(t, err)
+				assert.Zero(t, n)
+			},
+		},
+		{
+			name: "MSet with nothing to set",
+			run: func(t *testing.T, c ICache, mr *miniredis.Miniredis) {
+				assert.NoError(t, c.MSet(nil, time.Minute))
+				assert.Empty(t, mr.Keys())
+			},
+		},
+		{
+			name: "HGet scans into the destination",
+			run: func(t *testing.T, c ICache, mr *miniredis.Miniredis) {
+				require.NoError(t, c.HSet("user:1", map[string]interface{}{"name": "john", "age": 30}))
+
+				var age int
+				require.NoError(t, c.HGet(&age, "user:1", "age"))
+				assert.Equal(t, 30, age)
+
+				var name string
+				require.NoError(t, c.HGet(&name, "user:1", "name"))
+				assert.Equal(t, "john", name)
+
+				assert.ErrorIs(t, c.HGet(&name, "user:1", "missing"), redis.Nil)
+
+				values, err := c.HGetAll("user:1")
+				require.NoError(t, err)
+				assert.Equal(t, map[string]string{"name": "john", "age": "30"}, values)
+			},
+		},
+		{
+			name: "counters",
+			run: func(t *testing.T, c ICache, mr *miniredis.Miniredis) {
+				n, err := c.Incr("count")
+				require.NoError(t, err)
+				assert.Equal(t, int64(1), n)
+
+				n, err = c.IncrBy("count", 10)
+				require.NoError(t, err)
+				assert.Equal(t, int64(11), n)
+
+				n, err = c.Decr("count")
+				require.NoError(t, err)
+				assert.Equal(t, int64(10), n)
+			},
+		},
+		{
+			name: "TTL and Expire",
+			run: func(t *testing.T, c ICache, mr *miniredis.Miniredis) {
+				ok, err := c.Expire("missing", time.Minute)
+				require.NoError(t, err)
+				assert.False(t, ok)
+
+				ttl, err := c.TTL("missing")
+				require.NoError(t, err)
+				assert.Equal(t, time.Duration(-2), ttl)
+
+				require.NoError(t, c.Set("key", "value", 0))
+				ttl, err = c.TTL("key")
+				require.NoError(t, err)
+				assert.Equal(t, time.Duration(-1), ttl)
+
+				ok, err = c.Expire("key", time.Minute)
+				require.NoError(t, err)
+				assert.True(t, ok)
+
+				ttl, err = c.TTL("key")
+				require.NoError(t, err)
+				assert.Equal(t, time.Minute, ttl)
+			},
+		},
+		{
+			name: "sets",
+			run: func(t *testing.T, c ICache, mr *miniredis.Miniredis) {
+				require.NoError(t, c.SAdd("tags", "a", "b", "a"))
+
+				members, err := c.SMembers("tags")
+				require.NoError(t, err)
+				assert.ElementsMatch(t, []string{"a", "b"}, members)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, mr := newTestCache(t)
+			tt.run(t, c, mr)
+		})
+	}
+}
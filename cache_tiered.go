@@ -0,0 +1,465 @@
+package core
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Leakageonthelamp/go-leakage-core/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultTieredCacheSize    = 10000
+	defaultTieredCacheTTL     = time.Minute
+	defaultTieredCacheChannel = "cache:invalidate"
+	tieredCacheRetryDelay     = 500 * time.Millisecond
+)
+
+// ErrTieredCacheNotSupported is returned when the cache is not backed by redis
+var ErrTieredCacheNotSupported = errors.New("tiered cache needs a redis cache")
+
+type TieredCacheOptions struct {
+	Size    int           // max number of keys of the local tier, the least recently used key is evicted first, default is 10000
+	TTL     time.Duration // max time a key is kept in the local tier, it's shorter when the redis key expires first, default is 1m
+	Channel string        // redis pub/sub channel of the invalidations, default is cache:invalidate
+}
+
+type TieredCacheStats struct {
+	LocalHits    uint64
+	LocalMisses  uint64
+	RemoteHits   uint64
+	RemoteMisses uint64
+	Evictions    uint64
+	Size         int
+}
+
+// HitRatio return the ratio of the gets served by the local tier
+func (s TieredCacheStats) HitRatio() float64 {
+	total := s.LocalHits + s.LocalMisses
+	if total == 0 {
+		return 0
+	}
+
+	return float64(s.LocalHits) / float64(total)
+}
+
+type ITieredCache interface {
+	ICache
+	Stats() TieredCacheStats
+}
+
+// tieredInvalidation is the message of the pub/sub channel, every key of the local tier is removed when Keys is empty
+type tieredInvalidation struct {
+	Source string   `json:"source"`
+	Keys   []string `json:"keys,omitempty"`
+}
+
+type tieredCacheStats struct {
+	localHits    atomic.Uint64
+	localMisses  atomic.Uint64
+	remoteHits   atomic.Uint64
+	remoteMisses atomic.Uint64
+}
+
+// tieredCache keep the values of Get and GetJSON in a local LRU in front of redis.
+// The other commands go to redis, and the commands that change a string key remove it from the local tier of every pod
+type tieredCache struct {
+	ICache
+	rdb     *redis.Client
+	local   *tieredLocal
+	stats   *tieredCacheStats
+	options *TieredCacheOptions
+	id      string
+	pubsub  *redis.PubSub
+	ctx     context.Context
+}
+
+// NewTieredCache return an ICache with a bounded in-process tier in front of the redis of cache, it can be used as ContextOptions.Cache.
+// The local tiers of the pods are invalidated by redis pub/sub, and they are flushed when the subscription is reconnected
+func NewTieredCache(cache ICache, options *TieredCacheOptions) (ITieredCache, error) {
+	r, ok := cache.(redisClient)
+	if !ok {
+		return nil, ErrTieredCacheNotSupported
+	}
+
+	tieredOptions := TieredCacheOptions{}
+	if options != nil {
+		tieredOptions = *options
+	}
+	if tieredOptions.Size <= 0 {
+		tieredOptions.Size = defaultTieredCacheSize
+	}
+	if tieredOptions.TTL <= 0 {
+		tieredOptions.TTL = defaultTieredCacheTTL
+	}
+	if tieredOptions.Channel == "" {
+		tieredOptions.Channel = defaultTieredCacheChannel
+	}
+
+	rdb := r.client()
+	pubsub := rdb.Subscribe(context.Background(), tieredOptions.Channel)
+	if _, err := pubsub.Receive(context.Background()); err != nil {
+		_ = pubsub.Close()
+		return nil, err
+	}
+
+	c := &tieredCache{
+		ICache:  cache,
+		rdb:     rdb,
+		local:   newTieredLocal(tieredOptions.Size),
+		stats:   &tieredCacheStats{},
+		options: &tieredOptions,
+		id:      utils.GetUUID(),
+		pubsub:  pubsub,
+		ctx:     context.Background(),
+	}
+	go c.subscribe()
+
+	return c, nil
+}
+
+// subscribe apply the invalidations of the other pods until the subscription is closed
+func (c tieredCache) subscribe() {
+	for {
+		msg, err := c.pubsub.Receive(context.Background())
+		if err != nil {
+			if errors.Is(err, redis.ErrClosed) {
+				return
+			}
+
+			// the invalidations can be missed until the subscription is back
+			c.local.flush()
+			time.Sleep(tieredCacheRetryDelay)
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			c.local.flush()
+		case *redis.Message:
+			invalidation := tieredInvalidation{}
+			if err := json.Unmarshal([]byte(m.Payload), &invalidation); err != nil {
+				c.local.flush()
+				continue
+			}
+			if invalidation.Source == c.id {
+				continue
+			}
+			if len(invalidation.Keys) == 0 {
+				c.local.flush()
+				continue
+			}
+			c.local.del(invalidation.Keys...)
+		}
+	}
+}
+
+// WithContext return a copy of the cache whose commands are bound to ctx, the local tier is shared
+func (c tieredCache) WithContext(ctx context.Context) ICache {
+	c.ICache = c.ICache.WithContext(ctx)
+	c.ctx = ctx
+	return &c
+}
+
+func (c tieredCache) client() *redis.Client {
+	return c.rdb
+}
+
+func (c tieredCache) getContext() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+
+	return c.ctx
+}
+
+// Stats return the hits of the tiers since the cache is created
+func (c tieredCache) Stats() TieredCacheStats {
+	return TieredCacheStats{
+		LocalHits:    c.stats.localHits.Load(),
+		LocalMisses:  c.stats.localMisses.Load(),
+		RemoteHits:   c.stats.remoteHits.Load(),
+		RemoteMisses: c.stats.remoteMisses.Load(),
+		Evictions:    c.local.evictions.Load(),
+		Size:         c.local.len(),
+	}
+}
+
+func (c tieredCache) Close() {
+	_ = c.pubsub.Close()
+	c.ICache.Close()
+}
+
+// Get read key from the local tier, on a miss the value is read from redis and kept in the local tier
+func (c tieredCache) Get(dest interface{}, key string) error {
+	metrics := MetricsFromContext(c.ctx)
+	if value, ok := c.local.get(key); ok {
+		c.stats.localHits.Add(1)
+		if metrics != nil {
+			metrics.ObserveCache("get", CacheResultHit)
+		}
+		return redis.NewStringResult(value, nil).Scan(dest)
+	}
+
+	c.stats.localMisses.Add(1)
+	generation := c.local.getGeneration()
+
+	ctx := c.getContext()
+	pipe := c.rdb.Pipeline()
+	get := pipe.Get(ctx, key)
+	pttl := pipe.PTTL(ctx, key)
+	_, _ = pipe.Exec(ctx)
+
+	value, err := get.Result()
+	if metrics != nil {
+		switch {
+		case err == nil:
+			metrics.ObserveCache("get", CacheResultHit)
+		case errors.Is(err, redis.Nil):
+			metrics.ObserveCache("get", CacheResultMiss)
+		default:
+			metrics.ObserveCache("get", CacheResultError)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			c.stats.remoteMisses.Add(1)
+		}
+		return err
+	}
+
+	c.stats.remoteHits.Add(1)
+	ttl := c.options.TTL
+	if remaining := pttl.Val(); remaining > 0 && remaining < ttl {
+		ttl = remaining
+	}
+	c.local.set(key, value, ttl, generation)
+
+	return redis.NewStringResult(value, nil).Scan(dest)
+}
+
+func (c tieredCache) GetJSON(dest interface{}, key string) error {
+	var str string
+	err := c.Get(&str, key)
+	if err != nil {
+		return err
+	}
+
+	return utils.JSONParse(utils.StringToBytes(str), dest)
+}
+
+func (c tieredCache) Set(key string, value interface{}, expiration time.Duration) error {
+	if err := c.ICache.Set(key, value, expiration); err != nil {
+		return err
+	}
+
+	c.invalidate(key)
+	return nil
+}
+
+func (c tieredCache) SetNX(key string, value interface{}, expiration time.Duration) (bool, error) {
+	ok, err := c.ICache.SetNX(key, value, expiration)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	c.invalidate(key)
+	return ok, nil
+}
+
+func (c tieredCache) SetJSON(key string, value interface{}, expiration time.Duration) error {
+	return c.Set(key, utils.JSONToString(value), expiration)
+}
+
+func (c tieredCache) Del(key string) error {
+	if err := c.ICache.Del(key); err != nil {
+		return err
+	}
+
+	c.invalidate(key)
+	return nil
+}
+
+// DelPattern delete the keys that match pattern from redis, the local tier of every pod is flushed
+func (c tieredCache) DelPattern(pattern string) (int64, error) {
+	deleted, err := c.ICache.DelPattern(pattern)
+	if err != nil {
+		return deleted, err
+	}
+
+	c.invalidate()
+	return deleted, nil
+}
+
+func (c tieredCache) Incr(key string) (int64, error) {
+	return c.invalidateInt(key)(c.ICache.Incr(key))
+}
+
+func (c tieredCache) IncrBy(key string, value int64) (int64, error) {
+	return c.invalidateInt(key)(c.ICache.IncrBy(key, value))
+}
+
+func (c tieredCache) Decr(key string) (int64, error) {
+	return c.invalidateInt(key)(c.ICache.Decr(key))
+}
+
+func (c tieredCache) Expire(key string, expiration time.Duration) (bool, error) {
+	ok, err := c.ICache.Expire(key, expiration)
+	if err != nil || !ok {
+		return ok, err
+	}
+
+	c.invalidate(key)
+	return ok, nil
+}
+
+func (c tieredCache) MSet(values map[string]interface{}, expiration time.Duration) error {
+	if err := c.ICache.MSet(values, expiration); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	if len(keys) > 0 {
+		c.invalidate(keys...)
+	}
+
+	return nil
+}
+
+// invalidateInt invalidate key after a counter command succeeds
+func (c tieredCache) invalidateInt(key string) func(int64, error) (int64, error) {
+	return func(n int64, err error) (int64, error) {
+		if err != nil {
+			return n, err
+		}
+
+		c.invalidate(key)
+		return n, nil
+	}
+}
+
+// invalidate remove keys from the local tier and publish them to the other pods, every key is removed when keys is empty.
+// The command is already done in redis, so a failed publish is logged and the other pods keep their value up to the TTL of the local tier
+func (c tieredCache) invalidate(keys ...string) {
+	if len(keys) == 0 {
+		c.local.flush()
+	} else {
+		c.local.del(keys...)
+	}
+
+	payload := utils.JSONToString(tieredInvalidation{Source: c.id, Keys: keys})
+	if err := c.rdb.Publish(c.getContext(), c.options.Channel, payload).Err(); err != nil {
+		NewLoggerSimple().Error(err)
+	}
+}
+
+type tieredLocalItem struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// tieredLocal is a LRU of the local tier, the generation is changed by every invalidation
+// so a value read from redis before an invalidation is not kept
+type tieredLocal struct {
+	mu         sync.Mutex
+	size       int
+	items      map[string]*list.Element
+	order      *list.List
+	generation uint64
+	evictions  atomic.Uint64
+}
+
+func newTieredLocal(size int) *tieredLocal {
+	return &tieredLocal{size: size, items: map[string]*list.Element{}, order: list.New()}
+}
+
+func (l *tieredLocal) get(key string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.items[key]
+	if !ok {
+		return "", false
+	}
+
+	item := e.Value.(*tieredLocalItem)
+	if time.Now().After(item.expiresAt) {
+		l.order.Remove(e)
+		delete(l.items, key)
+		return "", false
+	}
+
+	l.order.MoveToFront(e)
+	return item.value, true
+}
+
+func (l *tieredLocal) getGeneration() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.generation
+}
+
+// set keep the value when there is no invalidation since generation
+func (l *tieredLocal) set(key string, value string, ttl time.Duration, generation uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.generation != generation {
+		return
+	}
+
+	item := &tieredLocalItem{key: key, value: value, expiresAt: time.Now().Add(ttl)}
+	if e, ok := l.items[key]; ok {
+		e.Value = item
+		l.order.MoveToFront(e)
+		return
+	}
+
+	l.items[key] = l.order.PushFront(item)
+	for l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*tieredLocalItem).key)
+		l.evictions.Add(1)
+	}
+}
+
+func (l *tieredLocal) del(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.generation++
+	for _, key := range keys {
+		if e, ok := l.items[key]; ok {
+			l.order.Remove(e)
+			delete(l.items, key)
+		}
+	}
+}
+
+func (l *tieredLocal) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.generation++
+	l.items = map[string]*list.Element{}
+	l.order.Init()
+}
+
+func (l *tieredLocal) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.order.Len()
+}
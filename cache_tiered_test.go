@@ -0,0 +1,182 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTieredTestCache return a tiered cache of its own connection to mr, as a pod would have
+func newTieredTestCache(t *testing.T, mr *miniredis.Miniredis, options *TieredCacheOptions) ITieredCache {
+	cache, err := DatabaseCache{Host: mr.Host(), Port: mr.Port()}.Connect()
+	require.NoError(t, err)
+
+	tiered, err := NewTieredCache(cache, options)
+	require.NoError(t, err)
+	t.Cleanup(tiered.Close)
+
+	return tiered
+}
+
+func getTieredTestValue(c ICache, key string) (string, error) {
+	var value string
+	err := c.Get(&value, key)
+	return value, err
+}
+
+func TestTieredCacheInvalidation(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a := newTieredTestCache(t, mr, nil)
+	b := newTieredTestCache(t, mr, nil)
+
+	assertValue := func(c ICache, key string, want string) {
+		t.Helper()
+		require.Eventually(t, func() bool {
+			value, err := getTieredTestValue(c, key)
+			return err == nil && value == want
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	require.NoError(t, a.Set("user:1", "john", 0))
+	assertValue(b, "user:1", "john")
+
+	// b serves its local tier until it's invalidated
+	require.NoError(t, mr.Set("user:1", "changed in redis only"))
+	assertValue(b, "user:1", "john")
+
+	require.NoError(t, a.Set("user:1", "jane", 0))
+	assertValue(b, "user:1", "jane")
+
+	require.NoError(t, a.Del("user:1"))
+	require.Eventually(t, func() bool {
+		_, err := getTieredTestValue(b, "user:1")
+		return err == redis.Nil
+	}, time.Second, 10*time.Millisecond)
+
+	_, err := a.Incr("count")
+	require.NoError(t, err)
+	assertValue(b, "count", "1")
+	_, err = a.Incr("count")
+	require.NoError(t, err)
+	assertValue(b, "count", "2")
+
+	// the writer drops its own local value at once
+	assertValue(a, "count", "2")
+	_, err = a.IncrBy("count", 10)
+	require.NoError(t, err)
+	value, err := getTieredTestValue(a, "count")
+	require.NoError(t, err)
+	assert.Equal(t, "12", value)
+
+	// DelPattern flushes every local tier
+	require.NoError(t, b.Set("user:2", "joe", 0))
+	assertValue(a, "user:2", "joe")
+	_, err = b.DelPattern("user:*")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		_, err := getTieredTestValue(a, "user:2")
+		return err == redis.Nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTieredCacheLocalTTL(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newTieredTestCache(t, mr, &TieredCacheOptions{TTL: 50 * time.Millisecond})
+
+	require.NoError(t, c.Set("user:1", "john", 0))
+	_, err := getTieredTestValue(c, "user:1")
+	require.NoError(t, err)
+
+	require.NoError(t, mr.Set("user:1", "changed in redis only"))
+	value, err := getTieredTestValue(c, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, "john", value)
+
+	time.Sleep(60 * time.Millisecond)
+	value, err = getTieredTestValue(c, "user:1")
+	require.NoError(t, err)
+	assert.Equal(t, "changed in redis only", value)
+}
+
+func TestTieredLocalGeneration(t *testing.T) {
+	l := newTieredLocal(10)
+
+	// a value read from redis before an invalidation is not kept
+	generation := l.getGeneration()
+	l.del("user:1")
+	l.set("user:1", "stale", time.Minute, generation)
+	_, ok := l.get("user:1")
+	assert.False(t, ok)
+
+	generation = l.getGeneration()
+	l.flush()
+	l.set("user:1", "stale", time.Minute, generation)
+	_, ok = l.get("user:1")
+	assert.False(t, ok)
+
+	l.set("user:1", "john", time.Minute, l.getGeneration())
+	value, ok := l.get("user:1")
+	assert.True(t, ok)
+	assert.Equal(t, "john", value)
+}
+
+func TestTieredLocalEviction(t *testing.T) {
+	l := newTieredLocal(2)
+	set := func(key string) {
+		l.set(key, key, time.Minute, l.getGeneration())
+	}
+
+	set("a")
+	set("b")
+	_, ok := l.get("a")
+	require.True(t, ok)
+
+	// b is the least recently used
+	set("c")
+	_, ok = l.get("b")
+	assert.False(t, ok)
+	_, ok = l.get("a")
+	assert.True(t, ok)
+	_, ok = l.get("c")
+	assert.True(t, ok)
+	assert.Equal(t, 2, l.len())
+	assert.Equal(t, uint64(1), l.evictions.Load())
+
+	// updating a key does not evict
+	set("a")
+	assert.Equal(t, 2, l.len())
+	assert.Equal(t, uint64(1), l.evictions.Load())
+}
+
+func TestTieredCacheStats(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c := newTieredTestCache(t, mr, &TieredCacheOptions{Size: 1})
+
+	require.NoError(t, mr.Set("a", "1"))
+	require.NoError(t, mr.Set("b", "2"))
+
+	for _, key := range []string{"a", "a", "a", "b", "missing"} {
+		_, _ = getTieredTestValue(c, key)
+	}
+
+	stats := c.Stats()
+	assert.Equal(t, TieredCacheStats{
+		LocalHits:    2,
+		LocalMisses:  3,
+		RemoteHits:   2,
+		RemoteMisses: 1,
+		Evictions:    1,
+		Size:         1,
+	}, stats)
+	assert.InDelta(t, 0.4, stats.HitRatio(), 0.0001)
+	assert.Zero(t, TieredCacheStats{}.HitRatio())
+}
+
+func TestNewTieredCacheNotSupported(t *testing.T) {
+	_, err := NewTieredCache(NewMockCache(), nil)
+	assert.ErrorIs(t, err, ErrTieredCacheNotSupported)
+}
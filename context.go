@@ -1,6 +1,8 @@
 package core
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
@@ -36,6 +38,10 @@ type IContext interface {
 	SetData(name string, data interface{})
 	SetUser(user *ContextUser)
 	GetUser() *ContextUser
+	GetContext() context.Context
+	WithContext(ctx context.Context) IContext
+	Transaction(fn func(txCtx IContext) error, opts ...*sql.TxOptions) IError
+	Metrics() IMetrics
 }
 
 type ContextOptions struct {
@@ -47,6 +53,7 @@ type ContextOptions struct {
 	Caches      map[string]ICache
 	ENV         IENV
 	MQ          IMQ
+	Metrics     IMetrics
 	contextType consts.ContextType
 	DATA        map[string]interface{}
 }
@@ -63,6 +70,11 @@ func NewContext(options *ContextOptions) IContext {
 		defer sentry.Flush(2 * time.Second)
 	}
 
+	ctx := context.Background()
+	if options.Metrics != nil {
+		ctx = ContextWithMetrics(ctx, options.Metrics)
+	}
+
 	return &coreContext{
 		database:       options.DB,
 		databases:      options.DBS,
@@ -74,6 +86,8 @@ func NewContext(options *ContextOptions) IContext {
 		caches:         options.Caches,
 		mq:             options.MQ,
 		data:           options.DATA,
+		metrics:        options.Metrics,
+		ctx:            ctx,
 	}
 }
 
@@ -90,6 +104,63 @@ type coreContext struct {
 	logger         ILogger
 	data           map[string]interface{}
 	user           *ContextUser
+	metrics        IMetrics
+	ctx            context.Context
+}
+
+// GetContext return the request-scoped context, every backend of the context respects its cancellation and deadline
+func (c *coreContext) GetContext() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+
+	return c.ctx
+}
+
+// WithContext return a shallow copy of the context with ctx as its request-scoped context
+func (c *coreContext) WithContext(ctx context.Context) IContext {
+	if c.metrics != nil && MetricsFromContext(ctx) == nil {
+		ctx = ContextWithMetrics(ctx, c.metrics)
+	}
+
+	newCtx := c.clone()
+	newCtx.ctx = ctx
+	return newCtx
+}
+
+// Metrics return the metrics registry, it returns nil when metrics are not configured
+func (c *coreContext) Metrics() IMetrics {
+	return c.metrics
+}
+
+// Transaction run fn in a database transaction, DB() of txCtx and every repository created from it use the transaction.
+// Calling Transaction on txCtx creates a savepoint, the transaction is rolled back when fn returns an error or panics
+func (c *coreContext) Transaction(fn func(txCtx IContext) error, opts ...*sql.TxOptions) IError {
+	db := c.DB()
+	if db == nil {
+		return c.NewError(gorm.ErrInvalidDB, databaseError)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		txCtx := c.clone()
+		txCtx.database = tx
+		return fn(txCtx)
+	}, opts...)
+	if err == nil {
+		return nil
+	}
+
+	if ierr, ok := err.(IError); ok {
+		return ierr
+	}
+
+	return c.NewError(err, databaseError)
+}
+
+func (c *coreContext) clone() *coreContext {
+	newCtx := *c
+	newCtx.logger = nil
+	return &newCtx
 }
 
 func (c *coreContext) SetUser(user *ContextUser) {
@@ -123,19 +194,27 @@ func (c *coreContext) SetData(name string, data interface{}) {
 }
 
 func (c *coreContext) Cache() ICache {
-	return c.cache
+	if c.cache == nil {
+		return nil
+	}
+
+	return c.cache.WithContext(c.GetContext())
 }
 
 func (c *coreContext) MQ() IMQ {
-	return c.mq
+	if c.mq == nil {
+		return nil
+	}
+
+	return c.mq.WithContext(c.GetContext())
 }
 
 func (c *coreContext) Caches(name string) ICache {
 	cache, ok := c.caches[name]
-	if !ok {
+	if !ok || cache == nil {
 		return nil
 	}
-	return cache
+	return cache.WithContext(c.GetContext())
 }
 
 func (c *coreContext) Requester() IRequester {
@@ -160,27 +239,35 @@ func (c *coreContext) Type() consts.ContextType {
 }
 
 func (c *coreContext) DB() *gorm.DB {
-	return c.database
+	if c.database == nil {
+		return nil
+	}
+
+	return c.database.WithContext(c.GetContext())
 }
 
 func (c *coreContext) DBS(name string) *gorm.DB {
 	db, ok := c.databases[name]
-	if !ok {
+	if !ok || db == nil {
 		return nil
 	}
-	return db
+	return db.WithContext(c.GetContext())
 }
 
 func (c *coreContext) DBMongo() IMongoDB {
-	return c.databaseMongo
+	if c.databaseMongo == nil {
+		return nil
+	}
+
+	return c.databaseMongo.WithContext(c.GetContext())
 }
 
 func (c *coreContext) DBSMongo(name string) IMongoDB {
 	db, ok := c.databasesMongo[name]
-	if !ok {
+	if !ok || db == nil {
 		return nil
 	}
-	return db
+	return db.WithContext(c.GetContext())
 }
 
 func (c *coreContext) NewError(err error, errorType IError, args ...interface{}) IError {
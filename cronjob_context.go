@@ -1,14 +1,22 @@
 package core
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
+	"runtime"
+	"sync/atomic"
 	"time"
 
 	"github.com/Leakageonthelamp/go-leakage-core/consts"
 	"github.com/go-co-op/gocron"
 )
 
+const defaultCronjobLockTTL = 30 * time.Second
+
 var cronjobError = Error{
 	Status:  http.StatusInternalServerError,
 	Code:    "CRONJOB_ERROR",
@@ -19,37 +27,173 @@ type ICronjobContext interface {
 	Job() *gocron.Scheduler
 	Start()
 	AddJob(job *gocron.Scheduler, handlerFunc func(ctx ICronjobContext) error)
+	AddSingletonJob(job *gocron.Scheduler, handlerFunc func(ctx ICronjobContext) error, options *CronjobSingletonOptions)
 }
 
 type CronjobContext struct {
 	IContext
-	cron *gocron.Scheduler
+	cron    *gocron.Scheduler
+	health  IHealthChecker
+	options *ContextOptions
+}
+
+func (c CronjobContext) WithContext(ctx context.Context) IContext {
+	return &CronjobContext{IContext: c.IContext.WithContext(ctx), cron: c.cron, health: c.health, options: c.options}
 }
 
+func (c CronjobContext) Transaction(fn func(txCtx IContext) error, opts ...*sql.TxOptions) IError {
+	return c.IContext.Transaction(func(txCtx IContext) error {
+		return fn(&CronjobContext{IContext: txCtx, cron: c.cron, health: c.health, options: c.options})
+	}, opts...)
+}
+
+// AddJob register the handler to the job, each run gets a context bound to the job's context.
+// Runs are recorded to the metrics by the first tag of the job or the name of the handler.
+// A running handler is not cancelled by the shutdown of the scheduler, it is waited for instead
 func (c CronjobContext) AddJob(job *gocron.Scheduler, handlerFunc func(ctx ICronjobContext) error) {
-	_, err := job.Do(func() {
+	c.addJob(job, handlerFunc, nil)
+}
+
+type CronjobSingletonOptions struct {
+	Key     string        // key of the lock prefixed by the service name, default is the first tag of the job or the name of the handler
+	LockTTL time.Duration // the lock is kept for LockTTL from the start of a run but released before the next run of the job, default is 30s
+}
+
+// AddSingletonJob register the handler like AddJob, but each run executes on the only pod that takes the lock of the job on ctx.Cache().
+// The lock is extended while the handler runs and kept until LockTTL after the start, so a pod whose clock is late skips the run.
+// The lock is never kept past 90% of the time until the next run, so a job scheduled more often than LockTTL still runs every time
+func (c CronjobContext) AddSingletonJob(job *gocron.Scheduler, handlerFunc func(ctx ICronjobContext) error, options *CronjobSingletonOptions) {
+	if options == nil {
+		options = &CronjobSingletonOptions{}
+	}
+
+	if c.Cache() == nil {
+		c.NewError(errors.New("singleton job needs a cache for its lock"), cronjobError)
+		return
+	}
+
+	c.addJob(job, handlerFunc, options)
+}
+
+func (c CronjobContext) addJob(job *gocron.Scheduler, handlerFunc func(ctx ICronjobContext) error, singleton *CronjobSingletonOptions) {
+	handlerName := runtime.FuncForPC(reflect.ValueOf(handlerFunc).Pointer()).Name()
+	// the job given to the handler is a copy without its next run, so the registered job is kept to find it
+	var scheduledJob atomic.Pointer[gocron.Job]
+	registeredJob, err := job.DoWithJobDetails(func(j gocron.Job) {
+		runCtx := c.WithContext(context.WithoutCancel(j.Context())).(*CronjobContext)
+		name := handlerName
+		if tags := j.Tags(); len(tags) > 0 {
+			name = tags[0]
+		}
+
+		start := time.Now()
+		if singleton != nil {
+			lock, ok := runCtx.lockJob(name, singleton)
+			if !ok {
+				return
+			}
+			defer func() {
+				var nextRun time.Time
+				if sj := scheduledJob.Load(); sj != nil {
+					nextRun = sj.NextRun()
+				}
+				runCtx.unlockJob(lock, getCronjobLockHold(singleton, start, nextRun)-time.Since(start))
+			}()
+		}
+
+		var err error
 		defer func() {
-			if err := recover(); err != nil {
-				err, ok := err.(error)
+			if r := recover(); r != nil {
+				var ok bool
+				err, ok = r.(error)
 				if !ok {
-					err = fmt.Errorf("%v", err)
+					err = fmt.Errorf("%v", r)
 				}
-				c.NewError(err, cronjobError)
+				runCtx.NewError(err, cronjobError)
+			}
+
+			if metrics := runCtx.Metrics(); metrics != nil {
+				metrics.ObserveCronjob(name, time.Since(start), err)
 			}
 		}()
 
-		err := handlerFunc(c)
+		err = handlerFunc(runCtx)
 		if err != nil {
-			c.NewError(err, cronjobError)
+			runCtx.NewError(err, cronjobError)
 		}
 	})
 	if err != nil {
 		c.NewError(err, cronjobError)
+		return
 	}
+
+	scheduledJob.Store(registeredJob)
 }
 
+// lockJob take the lock of the run, ok is false when the run is taken by another pod
+func (c CronjobContext) lockJob(name string, options *CronjobSingletonOptions) (lock ICacheLock, ok bool) {
+	key := options.Key
+	if key == "" {
+		key = name
+	}
+
+	lock, err := c.Cache().Lock(fmt.Sprintf("cronjob:%s:%s", c.ENV().Config().Service, key), &CacheLockOptions{TTL: getCronjobLockTTL(options), AutoExtend: true})
+	if errors.Is(err, ErrCacheLockNotAcquired) {
+		return nil, false
+	}
+	if err != nil {
+		c.NewError(err, cronjobError)
+		return nil, false
+	}
+
+	return lock, true
+}
+
+func (c CronjobContext) unlockJob(lock ICacheLock, hold time.Duration) {
+	err := lock.ReleaseAfter(hold)
+	if err != nil && !errors.Is(err, ErrCacheLockNotHeld) {
+		c.NewError(err, cronjobError)
+	}
+}
+
+func getCronjobLockTTL(options *CronjobSingletonOptions) time.Duration {
+	if options.LockTTL <= 0 {
+		return defaultCronjobLockTTL
+	}
+
+	return options.LockTTL
+}
+
+// getCronjobLockHold return how long the lock is kept from the start of a run, it's LockTTL
+// but at most 90% of the time until the next run so the lock is free again when the next run starts
+func getCronjobLockHold(options *CronjobSingletonOptions, start time.Time, nextRun time.Time) time.Duration {
+	hold := getCronjobLockTTL(options)
+	if interval := nextRun.Sub(start); interval > 0 && hold > interval*9/10 {
+		hold = interval * 9 / 10
+	}
+
+	return hold
+}
+
+// Start run the jobs and block until SIGINT or SIGTERM, the health and metrics endpoints are served on HEALTH_HOST when it is set.
+// On shutdown no job is started anymore, the running jobs are waited for up to SHUTDOWN_TIMEOUT
+// and then the connections of the context are closed
 func (c CronjobContext) Start() {
-	c.cron.StartBlocking()
+	server := startHealthServer(c.health, c.Metrics(), c.ENV().Config().HealthHost)
+	c.cron.StartAsync()
+
+	ctx, stop := NotifyShutdown()
+	defer stop()
+	<-ctx.Done()
+
+	fmt.Println(fmt.Sprintf("Cronjob Service: %s is shutting down", c.ENV().Config().Service))
+	c.health.SetReady(false)
+	if !waitTimeout(c.cron.Stop, getShutdownTimeout(c.ENV())) {
+		c.Log().Warn("the running jobs are not done before the shutdown timeout")
+	}
+
+	shutdownHealthServer(server)
+	CloseContextOptions(c.options)
 }
 
 func (c CronjobContext) Job() *gocron.Scheduler {
@@ -59,6 +203,7 @@ func (c CronjobContext) Job() *gocron.Scheduler {
 type CronjobContextOptions struct {
 	ContextOptions *ContextOptions
 	TimeLocation   *time.Location
+	HealthChecker  IHealthChecker // default checks every backend of ContextOptions
 }
 
 func NewCronjobContext(options *CronjobContextOptions) ICronjobContext {
@@ -70,6 +215,11 @@ func NewCronjobContext(options *CronjobContextOptions) ICronjobContext {
 	}
 	cron := gocron.NewScheduler(options.TimeLocation)
 
+	health := options.HealthChecker
+	if health == nil {
+		health = NewHealthChecker(ctxOptions)
+	}
+
 	fmt.Println(fmt.Sprintf("Cronjob Service: %s", options.ContextOptions.ENV.Config().Service))
-	return &CronjobContext{IContext: NewContext(ctxOptions), cron: cron}
+	return &CronjobContext{IContext: NewContext(ctxOptions), cron: cron, health: health, options: ctxOptions}
 }
@@ -0,0 +1,30 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCronjobLockHold(t *testing.T) {
+	start := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		options *CronjobSingletonOptions
+		nextRun time.Time
+		want    time.Duration
+	}{
+		{name: "default lock ttl", options: &CronjobSingletonOptions{}, nextRun: start.Add(time.Hour), want: defaultCronjobLockTTL},
+		{name: "lock ttl", options: &CronjobSingletonOptions{LockTTL: time.Minute}, nextRun: start.Add(time.Hour), want: time.Minute},
+		{name: "shorter interval", options: &CronjobSingletonOptions{}, nextRun: start.Add(10 * time.Second), want: 9 * time.Second},
+		{name: "unknown next run", options: &CronjobSingletonOptions{LockTTL: time.Minute}, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, getCronjobLockHold(tt.options, start, tt.nextRun))
+		})
+	}
+}
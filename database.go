@@ -35,6 +35,11 @@ const (
 	DatabaseDriverMYSQL    = "mysql"
 )
 
+var databaseError = Error{
+	Status:  http.StatusInternalServerError,
+	Code:    "DATABASE_ERROR",
+	Message: "database internal error"}
+
 type KeywordConditionWrapper struct {
 	Condition      KeywordCondition
 	KeywordOptions []KeywordOptions
@@ -122,6 +127,16 @@ func (db *Database) Connect() (*gorm.DB, error) {
 		return nil, err
 	}
 
+	err = newDB.Use(NewDatabaseMetricsPlugin())
+	if err != nil {
+		return nil, err
+	}
+
+	err = newDB.Use(NewDatabaseTracingPlugin())
+	if err != nil {
+		return nil, err
+	}
+
 	return newDB, nil
 }
 
@@ -138,19 +153,23 @@ func Paginate(db *gorm.DB, model interface{}, options *models.PageOptions) (*mod
 		}
 	}
 
-	var totalCount int64
-	err := db.Model(model).Count(&totalCount).Error
-	if err != nil {
-		return nil, err
+	var total *int64
+	if !options.SkipTotal {
+		var totalCount int64
+		err := db.Model(model).Count(&totalCount).Error
+		if err != nil {
+			return nil, err
+		}
+		total = &totalCount
 	}
 
-	err = db.Limit(int(options.Limit)).Offset(int(offset)).Find(model).Error
+	err := db.Limit(int(options.Limit)).Offset(int(offset)).Find(model).Error
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.PageResponse{
-		Total: totalCount,
+		Total: total,
 		Limit: options.Limit,
 		Count: int64(reflect.ValueOf(model).Elem().Len()),
 		Page:  options.Page,
@@ -0,0 +1,360 @@
+package core
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Leakageonthelamp/go-leakage-core/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidCursor is returned when the cursor of the page options can't be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	cursorDirectionNext = "next"
+	cursorDirectionPrev = "prev"
+)
+
+type cursorOrder struct {
+	Column string
+	Desc   bool
+}
+
+type sqlCursorValue struct {
+	Type  string      `json:"t,omitempty"`
+	Value interface{} `json:"v"`
+}
+
+type sqlCursor struct {
+	Direction string           `json:"d"`
+	Values    []sqlCursorValue `json:"v"`
+}
+
+type mongoCursor struct {
+	Direction string `bson:"d"`
+	Values    bson.A `bson:"v"`
+}
+
+// PaginateCursor paginate with keyset (cursor) pagination, the keyset is built from the OrderBy columns of the options.
+// The last OrderBy column should be unique and non-null e.g. "id desc" to get stable pages, "id desc" is used when OrderBy is empty
+func PaginateCursor(db *gorm.DB, model interface{}, options *models.PageOptions) (*models.CursorPageResponse, error) {
+	orders := getCursorOrders(options.OrderBy, "id")
+	cursor, err := decodeSQLCursor(options.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var total *int64
+	if !options.SkipTotal {
+		var totalCount int64
+		err = db.Model(model).Count(&totalCount).Error
+		if err != nil {
+			return nil, err
+		}
+		total = &totalCount
+	}
+
+	isPrev := cursor != nil && cursor.Direction == cursorDirectionPrev
+	queryOrders := orders
+	if isPrev {
+		queryOrders = reverseCursorOrders(orders)
+	}
+
+	if cursor != nil {
+		if len(cursor.Values) != len(orders) {
+			return nil, ErrInvalidCursor
+		}
+
+		values := make([]interface{}, len(cursor.Values))
+		for i, v := range cursor.Values {
+			values[i], err = v.decode()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		db = db.Clauses(clause.Where{Exprs: []clause.Expression{getSQLKeysetExpression(queryOrders, values)}})
+	}
+
+	for _, o := range queryOrders {
+		db = db.Order(clause.OrderByColumn{Column: clause.Column{Name: o.Column}, Desc: o.Desc})
+	}
+
+	err = db.Limit(int(options.Limit) + 1).Find(model).Error
+	if err != nil {
+		return nil, err
+	}
+
+	items := reflect.ValueOf(model).Elem()
+	hasMore := int64(items.Len()) > options.Limit
+	if hasMore {
+		items.Set(items.Slice(0, int(options.Limit)))
+	}
+
+	if isPrev {
+		reverseSlice(items)
+	}
+
+	res := &models.CursorPageResponse{
+		Total:   total,
+		Limit:   options.Limit,
+		Count:   int64(items.Len()),
+		Q:       options.Q,
+		OrderBy: options.OrderBy,
+	}
+
+	if items.Len() == 0 {
+		return res, nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err = stmt.Parse(model); err != nil {
+		return nil, err
+	}
+
+	encode := func(direction string, item reflect.Value) (string, error) {
+		values := make([]sqlCursorValue, len(orders))
+		for i, o := range orders {
+			field := stmt.Schema.LookUpField(o.Column[strings.LastIndex(o.Column, ".")+1:])
+			if field == nil {
+				return "", errors.New("order by column " + o.Column + " is not a field of the model")
+			}
+
+			value, _ := field.ValueOf(db.Statement.Context, reflect.Indirect(item))
+			values[i] = newSQLCursorValue(value)
+		}
+
+		return encodeCursor(json.Marshal(&sqlCursor{Direction: direction, Values: values}))
+	}
+
+	if hasMore || isPrev {
+		res.NextCursor, err = encode(cursorDirectionNext, items.Index(items.Len()-1))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if (cursor != nil && !isPrev) || (isPrev && hasMore) {
+		res.PrevCursor, err = encode(cursorDirectionPrev, items.Index(0))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+func getCursorOrders(orderBy []string, defaultColumn string) []cursorOrder {
+	orders := make([]cursorOrder, 0)
+	for _, o := range orderBy {
+		parameters := strings.Fields(o)
+		if len(parameters) == 0 {
+			continue
+		}
+
+		orders = append(orders, cursorOrder{
+			Column: parameters[0],
+			Desc:   len(parameters) > 1 && strings.EqualFold(parameters[1], "desc"),
+		})
+	}
+
+	if len(orders) == 0 {
+		orders = append(orders, cursorOrder{Column: defaultColumn, Desc: true})
+	}
+
+	return orders
+}
+
+func reverseCursorOrders(orders []cursorOrder) []cursorOrder {
+	reversed := make([]cursorOrder, len(orders))
+	for i, o := range orders {
+		reversed[i] = cursorOrder{Column: o.Column, Desc: !o.Desc}
+	}
+
+	return reversed
+}
+
+// getSQLKeysetExpression return (c1 > v1) OR (c1 = v1 AND c2 > v2) OR ..., the comparison follows the direction of each column
+func getSQLKeysetExpression(orders []cursorOrder, values []interface{}) clause.Expression {
+	exprs := make([]clause.Expression, 0, len(orders))
+	for i, o := range orders {
+		conds := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			conds = append(conds, clause.Eq{Column: clause.Column{Name: orders[j].Column}, Value: values[j]})
+		}
+
+		if o.Desc {
+			conds = append(conds, clause.Lt{Column: clause.Column{Name: o.Column}, Value: values[i]})
+		} else {
+			conds = append(conds, clause.Gt{Column: clause.Column{Name: o.Column}, Value: values[i]})
+		}
+
+		exprs = append(exprs, clause.And(conds...))
+	}
+
+	return clause.Or(exprs...)
+}
+
+// getMongoKeysetFilter is the mongo equivalent of getSQLKeysetExpression
+func getMongoKeysetFilter(orders []cursorOrder, values bson.A) bson.M {
+	exprs := make(bson.A, 0, len(orders))
+	for i, o := range orders {
+		cond := bson.M{}
+		for j := 0; j < i; j++ {
+			cond[orders[j].Column] = values[j]
+		}
+
+		if o.Desc {
+			cond[o.Column] = bson.M{"$lt": values[i]}
+		} else {
+			cond[o.Column] = bson.M{"$gt": values[i]}
+		}
+
+		exprs = append(exprs, cond)
+	}
+
+	return bson.M{"$or": exprs}
+}
+
+func getMongoCursorSort(orders []cursorOrder) bson.D {
+	sort := bson.D{}
+	for _, o := range orders {
+		if o.Desc {
+			sort = append(sort, bson.E{Key: o.Column, Value: -1})
+		} else {
+			sort = append(sort, bson.E{Key: o.Column, Value: 1})
+		}
+	}
+
+	return sort
+}
+
+func newSQLCursorValue(value interface{}) sqlCursorValue {
+	switch v := value.(type) {
+	case time.Time:
+		return sqlCursorValue{Type: "time", Value: v.Format(time.RFC3339Nano)}
+	case *time.Time:
+		if v != nil {
+			return sqlCursorValue{Type: "time", Value: v.Format(time.RFC3339Nano)}
+		}
+	}
+
+	return sqlCursorValue{Value: value}
+}
+
+func (v sqlCursorValue) decode() (interface{}, error) {
+	switch value := v.Value.(type) {
+	case string:
+		if v.Type == "time" {
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+
+			return t, nil
+		}
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i, nil
+		}
+
+		return value.Float64()
+	}
+
+	return v.Value, nil
+}
+
+func decodeSQLCursor(s string) (*sqlCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := &sqlCursor{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err = decoder.Decode(cursor); err != nil || !isCursorDirection(cursor.Direction) {
+		return nil, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
+
+func decodeMongoCursor(s string) (*mongoCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := &mongoCursor{}
+	if err = bson.UnmarshalExtJSON(b, true, cursor); err != nil || !isCursorDirection(cursor.Direction) {
+		return nil, ErrInvalidCursor
+	}
+
+	// the cursor is sent by the client, a document, an array or a regex would be read by mongo as a condition of the filter
+	for _, value := range cursor.Values {
+		if !isMongoCursorValue(value) {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	return cursor, nil
+}
+
+func isMongoCursorValue(value interface{}) bool {
+	switch value.(type) {
+	case bson.D, bson.M, bson.A, primitive.Regex, primitive.JavaScript, primitive.CodeWithScope:
+		return false
+	}
+
+	return true
+}
+
+func encodeMongoCursor(direction string, orders []cursorOrder, doc bson.Raw) (string, error) {
+	values := make(bson.A, len(orders))
+	for i, o := range orders {
+		value, err := doc.LookupErr(strings.Split(o.Column, ".")...)
+		if err != nil {
+			return "", errors.New("order by field " + o.Column + " is not in the document")
+		}
+		values[i] = value
+	}
+
+	return encodeCursor(bson.MarshalExtJSON(&mongoCursor{Direction: direction, Values: values}, true, false))
+}
+
+func encodeCursor(b []byte, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func isCursorDirection(direction string) bool {
+	return direction == cursorDirectionNext || direction == cursorDirectionPrev
+}
+
+func reverseSlice(items reflect.Value) {
+	swap := reflect.Swapper(items.Interface())
+	for i, j := 0, items.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
@@ -0,0 +1,251 @@
+package core
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Leakageonthelamp/go-leakage-core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type cursorTestUser struct {
+	ID        int64
+	Name      string
+	CreatedAt time.Time
+}
+
+func getCursorTestUserIDs(items []cursorTestUser) []int64 {
+	ids := make([]int64, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+
+	return ids
+}
+
+func TestGetCursorOrders(t *testing.T) {
+	tests := []struct {
+		name    string
+		orderBy []string
+		want    []cursorOrder
+	}{
+		{name: "default", orderBy: nil, want: []cursorOrder{{Column: "id", Desc: true}}},
+		{name: "blank", orderBy: []string{" "}, want: []cursorOrder{{Column: "id", Desc: true}}},
+		{
+			name:    "directions",
+			orderBy: []string{"created_at DESC", "name", "id asc"},
+			want:    []cursorOrder{{Column: "created_at", Desc: true}, {Column: "name"}, {Column: "id"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, getCursorOrders(tt.orderBy, "id"))
+		})
+	}
+}
+
+func TestSQLCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2023, 10, 1, 12, 30, 0, 123456789, time.UTC)
+	s, err := encodeCursor(json.Marshal(&sqlCursor{
+		Direction: cursorDirectionPrev,
+		Values:    []sqlCursorValue{newSQLCursorValue(createdAt), newSQLCursorValue("john"), newSQLCursorValue(int64(42)), newSQLCursorValue(1.5)},
+	}))
+	require.NoError(t, err)
+
+	cursor, err := decodeSQLCursor(s)
+	require.NoError(t, err)
+	assert.Equal(t, cursorDirectionPrev, cursor.Direction)
+
+	want := []interface{}{createdAt, "john", int64(42), 1.5}
+	require.Len(t, cursor.Values, len(want))
+	for i, v := range cursor.Values {
+		value, err := v.decode()
+		require.NoError(t, err)
+		assert.Equal(t, want[i], value)
+	}
+}
+
+func TestMongoCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2023, 10, 1, 12, 30, 0, 0, time.UTC)
+	doc, err := bson.Marshal(bson.M{"_id": "a1", "profile": bson.M{"age": int32(30)}, "created_at": createdAt})
+	require.NoError(t, err)
+
+	orders := []cursorOrder{{Column: "created_at", Desc: true}, {Column: "profile.age"}, {Column: "_id"}}
+	s, err := encodeMongoCursor(cursorDirectionNext, orders, doc)
+	require.NoError(t, err)
+
+	cursor, err := decodeMongoCursor(s)
+	require.NoError(t, err)
+	assert.Equal(t, cursorDirectionNext, cursor.Direction)
+
+	assert.Equal(t, bson.A{primitive.NewDateTimeFromTime(createdAt), int32(30), "a1"}, cursor.Values)
+
+	_, err = encodeMongoCursor(cursorDirectionNext, []cursorOrder{{Column: "missing"}}, doc)
+	assert.Error(t, err)
+}
+
+func TestDecodeCursorMalformed(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "not a cursor!"},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte(`{"d":"next","v":[1]}`))},
+		{name: "not json", cursor: encode("next:1")},
+		{name: "unknown direction", cursor: encode(`{"d":"up","v":[1]}`)},
+		{name: "missing direction", cursor: encode(`{"v":[1]}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := decodeSQLCursor(tt.cursor)
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+			assert.Nil(t, cursor)
+
+			mongoCursor, err := decodeMongoCursor(tt.cursor)
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+			assert.Nil(t, mongoCursor)
+		})
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		cursor, err := decodeSQLCursor("")
+		assert.NoError(t, err)
+		assert.Nil(t, cursor)
+	})
+
+	t.Run("invalid time", func(t *testing.T) {
+		cursor, err := decodeSQLCursor(encode(`{"d":"next","v":[{"t":"time","v":"yesterday"}]}`))
+		require.NoError(t, err)
+
+		_, err = cursor.Values[0].decode()
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+}
+
+func TestDecodeMongoCursorTampered(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "operator document", cursor: encode(`{"d":"next","v":[{"$ne":null}]}`)},
+		{name: "where operator", cursor: encode(`{"d":"next","v":["a1",{"$where":"sleep(1000)"}]}`)},
+		{name: "array", cursor: encode(`{"d":"next","v":[["a1","a2"]]}`)},
+		{name: "regex", cursor: encode(`{"d":"next","v":[{"$regularExpression":{"pattern":".*","options":""}}]}`)},
+		{name: "javascript", cursor: encode(`{"d":"next","v":[{"$code":"true"}]}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := decodeMongoCursor(tt.cursor)
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+			assert.Nil(t, cursor)
+		})
+	}
+}
+
+func TestGetSQLKeysetExpression(t *testing.T) {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	require.NoError(t, err)
+
+	orders := []cursorOrder{{Column: "created_at", Desc: true}, {Column: "name"}, {Column: "id", Desc: true}}
+	stmt := db.Clauses(clause.Where{Exprs: []clause.Expression{getSQLKeysetExpression(orders, []interface{}{"2023-10-01", "john", 42})}}).
+		Find(&[]cursorTestUser{}).Statement
+
+	assert.Equal(t,
+		`SELECT * FROM "cursor_test_users" WHERE ("created_at" < $1 OR ("created_at" = $2 AND "name" > $3) OR ("created_at" = $4 AND "name" = $5 AND "id" < $6))`,
+		stmt.SQL.String())
+	assert.Equal(t, []interface{}{"2023-10-01", "2023-10-01", "john", "2023-10-01", "john", 42}, stmt.Vars)
+}
+
+func TestGetMongoKeysetFilter(t *testing.T) {
+	orders := []cursorOrder{{Column: "created_at", Desc: true}, {Column: "_id"}}
+
+	assert.Equal(t, bson.M{"$or": bson.A{
+		bson.M{"created_at": bson.M{"$lt": "2023-10-01"}},
+		bson.M{"created_at": "2023-10-01", "_id": bson.M{"$gt": "a1"}},
+	}}, getMongoKeysetFilter(orders, bson.A{"2023-10-01", "a1"}))
+	assert.Equal(t, bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: 1}}, getMongoCursorSort(orders))
+}
+
+func TestPaginateCursor(t *testing.T) {
+	db := newTestSQLite(t)
+	require.NoError(t, db.AutoMigrate(&cursorTestUser{}))
+
+	// created_at has duplicates so the pages are only stable with the id tiebreaker
+	day := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	users := []cursorTestUser{
+		{ID: 1, Name: "a", CreatedAt: day},
+		{ID: 2, Name: "b", CreatedAt: day},
+		{ID: 3, Name: "c", CreatedAt: day},
+		{ID: 4, Name: "d", CreatedAt: day.Add(time.Hour)},
+		{ID: 5, Name: "e", CreatedAt: day.Add(time.Hour)},
+		{ID: 6, Name: "f", CreatedAt: day.Add(2 * time.Hour)},
+		{ID: 7, Name: "g", CreatedAt: day.Add(2 * time.Hour)},
+	}
+	require.NoError(t, db.Create(&users).Error)
+
+	wantPages := [][]int64{{7, 6, 5}, {4, 3, 2}, {1}}
+	paginate := func(cursor string) ([]int64, *models.CursorPageResponse) {
+		items := make([]cursorTestUser, 0)
+		res, err := PaginateCursor(db, &items, &models.PageOptions{
+			Limit:   3,
+			OrderBy: []string{"created_at desc", "id desc"},
+			Cursor:  cursor,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, res.Total)
+		assert.Equal(t, int64(7), *res.Total)
+		assert.Equal(t, int64(len(items)), res.Count)
+		return getCursorTestUserIDs(items), res
+	}
+
+	ids, res := paginate("")
+	assert.Equal(t, wantPages[0], ids)
+	assert.Empty(t, res.PrevCursor)
+
+	for i := 1; i < len(wantPages); i++ {
+		require.NotEmpty(t, res.NextCursor, "page %d", i)
+		ids, res = paginate(res.NextCursor)
+		assert.Equal(t, wantPages[i], ids, "next page %d", i)
+		assert.NotEmpty(t, res.PrevCursor)
+	}
+	assert.Empty(t, res.NextCursor)
+
+	for i := len(wantPages) - 1; i > 0; i-- {
+		require.NotEmpty(t, res.PrevCursor, "page %d", i)
+		ids, res = paginate(res.PrevCursor)
+		assert.Equal(t, wantPages[i-1], ids, "prev page %d", i-1)
+		assert.NotEmpty(t, res.NextCursor)
+	}
+	assert.Empty(t, res.PrevCursor)
+}
+
+func TestPaginateCursor_SkipTotal(t *testing.T) {
+	db := newTestSQLite(t)
+	require.NoError(t, db.AutoMigrate(&cursorTestUser{}))
+	require.NoError(t, db.Create(&[]cursorTestUser{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}).Error)
+
+	items := make([]cursorTestUser, 0)
+	res, err := PaginateCursor(db, &items, &models.PageOptions{Limit: 1, SkipTotal: true})
+	require.NoError(t, err)
+	assert.Nil(t, res.Total)
+	assert.Equal(t, []int64{2}, getCursorTestUserIDs(items))
+}
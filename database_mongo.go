@@ -3,14 +3,20 @@ package core
 import (
 	"context"
 	"errors"
-	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/Leakageonthelamp/go-leakage-core/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -50,8 +56,7 @@ func NewDatabaseMongo(env *ENVConfig) *DatabaseMongo {
 func (db *DatabaseMongo) Connect() (IMongoDB, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), connectTimeOut)
 	defer cancel()
-	client, err := mongo.Connect(ctx,
-		options.Client().ApplyURI(fmt.Sprintf("mongodb://%s:%s@%s:%s", db.UserName, db.Password, db.Host, db.Port)))
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(db.getURI()).SetMonitor(newMongoMonitor()))
 
 	if err != nil {
 		return nil, err
@@ -64,6 +69,53 @@ func (db *DatabaseMongo) Connect() (IMongoDB, error) {
 	return &MongoDB{database: client.Database(db.Name), databaseClient: client}, nil
 }
 
+// getURI return the connection string, the credentials are escaped as they can contain @, : or /
+func (db *DatabaseMongo) getURI() string {
+	uri := &url.URL{Scheme: "mongodb", Host: net.JoinHostPort(db.Host, db.Port)}
+	if db.UserName != "" {
+		uri.User = url.UserPassword(db.UserName, db.Password)
+	}
+
+	return uri.String()
+}
+
+// newMongoMonitor record the commands to the metrics and a span of the command's context
+func newMongoMonitor() *event.CommandMonitor {
+	spans := &sync.Map{}
+	endCommandSpan := func(requestID int64, err error) {
+		if span, ok := spans.LoadAndDelete(requestID); ok {
+			endSpan(span.(trace.Span), err)
+		}
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			if !trace.SpanContextFromContext(ctx).IsValid() {
+				return
+			}
+
+			_, span := Tracer().Start(ctx, "mongodb "+e.CommandName, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+				attribute.String("db.system", "mongodb"),
+				attribute.String("db.name", e.DatabaseName),
+				attribute.String("db.operation", e.CommandName),
+			))
+			spans.Store(e.RequestID, span)
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			endCommandSpan(e.RequestID, nil)
+			if metrics := MetricsFromContext(ctx); metrics != nil {
+				metrics.ObserveDBQuery("mongodb", e.CommandName, time.Duration(e.DurationNanos), nil)
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			endCommandSpan(e.RequestID, errors.New(e.Failure))
+			if metrics := MetricsFromContext(ctx); metrics != nil {
+				metrics.ObserveDBQuery("mongodb", e.CommandName, time.Duration(e.DurationNanos), errors.New(e.Failure))
+			}
+		},
+	}
+}
+
 type IMongoDB interface {
 	DB() *mongo.Database
 	Create(coll string, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
@@ -72,9 +124,12 @@ type IMongoDB interface {
 	FindAggregateOne(dest interface{}, coll string, pipeline interface{}, opts ...*options.AggregateOptions) error
 	Find(dest interface{}, coll string, filter interface{}, opts ...*options.FindOptions) error
 	FindPagination(dest interface{}, coll string, filter interface{}, pageOptions *models.PageOptions, opts ...*options.FindOptions) (*models.PageResponse, error)
+	FindCursorPagination(dest interface{}, coll string, filter interface{}, pageOptions *models.PageOptions, opts ...*options.FindOptions) (*models.CursorPageResponse, error)
+	FindAggregateCursorPagination(dest interface{}, coll string, pipeline interface{}, pageOptions *models.PageOptions, opts ...*options.AggregateOptions) (*models.CursorPageResponse, error)
 	FindOne(dest interface{}, coll string, filter interface{}, opts ...*options.FindOneOptions) error
 	FindOneAndUpdate(dest interface{}, coll string, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) error
 	UpdateOne(coll string, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	UpdateMany(coll string, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
 	Count(coll string, filter interface{}, opts ...*options.CountOptions) (int64, error)
 	Drop(coll string) error
 	DeleteOne(coll string, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
@@ -86,19 +141,69 @@ type IMongoDB interface {
 	DropIndex(coll string, name string, opts ...*options.DropIndexesOptions) (*MongoDropIndexResult, error)
 	DropAll(coll string, opts ...*options.DropIndexesOptions) (*MongoDropIndexResult, error)
 	ListIndex(coll string, opts ...*options.ListIndexesOptions) ([]MongoListIndexResult, error)
+	WithContext(ctx context.Context) IMongoDB
+	WithTransaction(fn func(tx IMongoDB) error, opts ...*options.TransactionOptions) error
+	Ping() error
 }
 
 type MongoDB struct {
 	database       *mongo.Database
 	databaseClient *mongo.Client
+	ctx            context.Context
 }
 
 func (m MongoDB) Helper() IMongoDBHelper {
 	return NewMongoHelper()
 }
 
+// WithContext return a copy of the database whose queries are bound to ctx
+func (m MongoDB) WithContext(ctx context.Context) IMongoDB {
+	m.ctx = ctx
+	return &m
+}
+
+// WithTransaction run fn in a multi-document transaction, every operation of tx joins the transaction.
+// Transient transaction errors are retried and the transaction is aborted when fn returns an error,
+// calling WithTransaction on tx joins the running transaction
+func (m MongoDB) WithTransaction(fn func(tx IMongoDB) error, opts ...*options.TransactionOptions) error {
+	ctx := m.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(m.WithContext(ctx))
+	}
+
+	session, err := m.databaseClient.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(m.WithContext(sessCtx))
+	}, opts...)
+
+	return err
+}
+
+// getContext return the query context, it's cancelled by the bound context or after queryTimeOut whichever comes first
 func (m MongoDB) getContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), queryTimeOut)
+	ctx := m.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	return context.WithTimeout(ctx, queryTimeOut)
+}
+
+// Ping check the connection to the primary
+func (m MongoDB) Ping() error {
+	ctx, cancel := m.getContext()
+	defer cancel()
+
+	return m.databaseClient.Ping(ctx, readpref.Primary())
 }
 
 func (m MongoDB) Close() {
@@ -114,13 +219,33 @@ func (m MongoDB) getSkips(pageOptions *models.PageOptions) int64 {
 	return pageOptions.Limit * (pageOptions.Page - 1)
 }
 
+// getMongoPageResponse return the page of the documents decoded to dest, the page options are not set when pageOptions is nil
+func getMongoPageResponse(total *int64, dest interface{}, pageOptions *models.PageOptions) *models.PageResponse {
+	res := &models.PageResponse{
+		Total: total,
+		Count: int64(reflect.ValueOf(dest).Elem().Len()),
+	}
+
+	if pageOptions != nil {
+		res.Limit = pageOptions.Limit
+		res.Page = pageOptions.Page
+		res.Q = pageOptions.Q
+	}
+
+	return res
+}
+
 func (m MongoDB) FindPagination(dest interface{}, coll string, filter interface{}, pageOptions *models.PageOptions, opts ...*options.FindOptions) (*models.PageResponse, error) {
 	ctx, cancel := m.getContext()
 	defer cancel()
 
-	totalCount, err := m.Count(coll, filter)
-	if err != nil {
-		return nil, err
+	var total *int64
+	if pageOptions == nil || !pageOptions.SkipTotal {
+		totalCount, err := m.Count(coll, filter)
+		if err != nil {
+			return nil, err
+		}
+		total = &totalCount
 	}
 
 	if pageOptions != nil {
@@ -134,18 +259,187 @@ func (m MongoDB) FindPagination(dest interface{}, coll string, filter interface{
 	}
 	defer cur.Close(ctx)
 
-	var count int64 = 0
+	err = cur.All(ctx, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	return getMongoPageResponse(total, dest, pageOptions), nil
+}
+
+// FindCursorPagination find with keyset (cursor) pagination, the keyset is built from the OrderBy fields of the page options.
+// The last OrderBy field should be unique e.g. "_id desc" to get stable pages, "_id desc" is used when OrderBy is empty
+func (m MongoDB) FindCursorPagination(dest interface{}, coll string, filter interface{}, pageOptions *models.PageOptions, opts ...*options.FindOptions) (*models.CursorPageResponse, error) {
+	orders := getCursorOrders(pageOptions.OrderBy, "_id")
+	cursor, err := decodeMongoCursor(pageOptions.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	var total *int64
+	if !pageOptions.SkipTotal {
+		totalCount, err := m.Count(coll, filter)
+		if err != nil {
+			return nil, err
+		}
+		total = &totalCount
+	}
+
+	isPrev := cursor != nil && cursor.Direction == cursorDirectionPrev
+	queryOrders := orders
+	if isPrev {
+		queryOrders = reverseCursorOrders(orders)
+	}
+
+	if cursor != nil {
+		if len(cursor.Values) != len(orders) {
+			return nil, ErrInvalidCursor
+		}
+
+		filter = bson.M{"$and": bson.A{filter, getMongoKeysetFilter(queryOrders, cursor.Values)}}
+	}
+
+	ctx, cancel := m.getContext()
+	defer cancel()
+
+	opts = append(opts, options.Find().SetSort(getMongoCursorSort(queryOrders)).SetLimit(pageOptions.Limit+1))
+	cur, err := m.DB().Collection(coll).Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	return m.getCursorPageResponse(ctx, cur, dest, orders, cursor, pageOptions, total)
+}
+
+func (m MongoDB) FindAggregateCursorPagination(dest interface{}, coll string, pipeline interface{}, pageOptions *models.PageOptions, opts ...*options.AggregateOptions) (*models.CursorPageResponse, error) {
+	orders := getCursorOrders(pageOptions.OrderBy, "_id")
+	cursor, err := decodeMongoCursor(pageOptions.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	pips, ok := pipeline.([]bson.M)
+	if !ok {
+		return nil, errors.New("pipeline is not []bson.M")
+	}
+
+	var total *int64
+	if !pageOptions.SkipTotal {
+		type Count struct {
+			Count int64 `bson:"_count"`
+		}
+		totalModel := &Count{}
+		countPipeline := append(append([]bson.M{}, pips...), bson.M{
+			"$count": "_count",
+		})
+		err = m.FindAggregateOne(totalModel, coll, countPipeline)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		total = &totalModel.Count
+	}
+
+	isPrev := cursor != nil && cursor.Direction == cursorDirectionPrev
+	queryOrders := orders
+	if isPrev {
+		queryOrders = reverseCursorOrders(orders)
+	}
+
+	pagePipeline := append([]bson.M{}, pips...)
+	if cursor != nil {
+		if len(cursor.Values) != len(orders) {
+			return nil, ErrInvalidCursor
+		}
+
+		pagePipeline = append(pagePipeline, bson.M{
+			"$match": getMongoKeysetFilter(queryOrders, cursor.Values),
+		})
+	}
+
+	pagePipeline = append(pagePipeline,
+		bson.M{
+			"$sort": getMongoCursorSort(queryOrders),
+		}, bson.M{
+			"$limit": pageOptions.Limit + 1,
+		})
+
+	ctx, cancel := m.getContext()
+	defer cancel()
+
+	cur, err := m.DB().Collection(coll).Aggregate(ctx, pagePipeline, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	return m.getCursorPageResponse(ctx, cur, dest, orders, cursor, pageOptions, total)
+}
+
+func (m MongoDB) getCursorPageResponse(ctx context.Context, cur *mongo.Cursor, dest interface{}, orders []cursorOrder, cursor *mongoCursor,
+	pageOptions *models.PageOptions, total *int64) (*models.CursorPageResponse, error) {
+
+	docs := make([]bson.Raw, 0)
 	for cur.Next(ctx) {
-		count++
+		docs = append(docs, append(bson.Raw{}, cur.Current...))
 	}
 
-	return &models.PageResponse{
-		Total: totalCount,
-		Limit: pageOptions.Limit,
-		Count: count,
-		Page:  pageOptions.Page,
-		Q:     pageOptions.Q,
-	}, cur.All(ctx, dest)
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	isPrev := cursor != nil && cursor.Direction == cursorDirectionPrev
+	hasMore := int64(len(docs)) > pageOptions.Limit
+	if hasMore {
+		docs = docs[:pageOptions.Limit]
+	}
+
+	if isPrev {
+		reverseSlice(reflect.ValueOf(docs))
+	}
+
+	items := reflect.ValueOf(dest).Elem()
+	items.Set(reflect.MakeSlice(items.Type(), 0, len(docs)))
+	for _, doc := range docs {
+		item := reflect.New(items.Type().Elem())
+		if err := bson.Unmarshal(doc, item.Interface()); err != nil {
+			return nil, err
+		}
+		items.Set(reflect.Append(items, item.Elem()))
+	}
+
+	res := &models.CursorPageResponse{
+		Total:   total,
+		Limit:   pageOptions.Limit,
+		Count:   int64(len(docs)),
+		Q:       pageOptions.Q,
+		OrderBy: pageOptions.OrderBy,
+	}
+
+	if len(docs) == 0 {
+		return res, nil
+	}
+
+	var err error
+	if hasMore || isPrev {
+		res.NextCursor, err = encodeMongoCursor(cursorDirectionNext, orders, docs[len(docs)-1])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if (cursor != nil && !isPrev) || (isPrev && hasMore) {
+		res.PrevCursor, err = encodeMongoCursor(cursorDirectionPrev, orders, docs[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
 }
 
 func (m MongoDB) Count(coll string, filter interface{}, opts ...*options.CountOptions) (int64, error) {
@@ -173,17 +467,21 @@ func (m MongoDB) FindAggregatePagination(dest interface{}, coll string, pipeline
 	type Count struct {
 		Count int64 `bson:"_count"`
 	}
-	totalModel := &Count{}
+	var total *int64
 	countPipeline, ok := pipeline.([]bson.M)
 	if !ok {
 		return nil, errors.New("pipeline is not []bson.M")
 	}
-	countPipeline = append(countPipeline, bson.M{
-		"$count": "_count",
-	})
-	err := m.FindAggregateOne(totalModel, coll, countPipeline)
-	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
-		return nil, err
+	if pageOptions == nil || !pageOptions.SkipTotal {
+		totalModel := &Count{}
+		countPipeline = append(countPipeline, bson.M{
+			"$count": "_count",
+		})
+		err := m.FindAggregateOne(totalModel, coll, countPipeline)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		total = &totalModel.Count
 	}
 
 	if pageOptions != nil {
@@ -209,18 +507,12 @@ func (m MongoDB) FindAggregatePagination(dest interface{}, coll string, pipeline
 	}
 	defer cur.Close(ctx)
 
-	var count int64 = 0
-	for cur.Next(ctx) {
-		count++
+	err = cur.All(ctx, dest)
+	if err != nil {
+		return nil, err
 	}
 
-	return &models.PageResponse{
-		Total: totalModel.Count,
-		Limit: pageOptions.Limit,
-		Count: count,
-		Page:  pageOptions.Page,
-		Q:     pageOptions.Q,
-	}, cur.All(ctx, dest)
+	return getMongoPageResponse(total, dest, pageOptions), nil
 }
 
 func (m MongoDB) FindAggregateOne(dest interface{}, coll string, pipeline interface{}, opts ...*options.AggregateOptions) error {
@@ -261,6 +553,15 @@ func (m MongoDB) UpdateOne(coll string, filter interface{}, update interface{},
 	return m.DB().Collection(coll).UpdateOne(ctx, filter, update, opts...)
 }
 
+func (m MongoDB) UpdateMany(coll string, filter interface{}, update interface{},
+	opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+
+	ctx, cancel := m.getContext()
+	defer cancel()
+
+	return m.DB().Collection(coll).UpdateMany(ctx, filter, update, opts...)
+}
+
 func (m MongoDB) FindOneAndUpdate(dest interface{}, coll string, filter interface{}, update interface{},
 	opts ...*options.FindOneAndUpdateOptions) error {
 
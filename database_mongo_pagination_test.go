@@ -0,0 +1,152 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/Leakageonthelamp/go-leakage-core/models"
+	"github.com/Leakageonthelamp/go-leakage-core/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+type mongoPaginationTestUser struct {
+	ID   string `bson:"_id"`
+	Name string `bson:"name"`
+}
+
+func newMongoPaginationTestDB(mt *mtest.T) *MongoDB {
+	return &MongoDB{database: mt.DB, databaseClient: mt.Client}
+}
+
+// getMongoTestCommand return the command of the started event of name
+func getMongoTestCommand(mt *mtest.T, name string) bson.Raw {
+	for {
+		e := mt.GetStartedEvent()
+		if e == nil {
+			return nil
+		}
+
+		if e.CommandName == name {
+			return e.Command
+		}
+	}
+}
+
+func TestMongoDB_FindPagination(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	users := []bson.D{
+		{{Key: "_id", Value: "u3"}, {Key: "name", Value: "jim"}},
+		{{Key: "_id", Value: "u4"}, {Key: "name", Value: "joe"}},
+	}
+
+	mt.Run("page", func(mt *mtest.T) {
+		ns := mt.DB.Name() + ".users"
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: int32(5)}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, users...),
+		)
+
+		dest := make([]mongoPaginationTestUser, 0)
+		res, err := newMongoPaginationTestDB(mt).FindPagination(&dest, "users", bson.M{}, &models.PageOptions{Page: 2, Limit: 2, Q: "j"})
+		require.NoError(t, err)
+
+		// the count of the page is the number of decoded documents
+		assert.Equal(t, []mongoPaginationTestUser{{ID: "u3", Name: "jim"}, {ID: "u4", Name: "joe"}}, dest)
+		assert.Equal(t, &models.PageResponse{Total: utils.ToPointer(int64(5)), Limit: 2, Count: 2, Page: 2, Q: "j"}, res)
+
+		find := getMongoTestCommand(mt, "find")
+		require.NotNil(t, find)
+		assert.Equal(t, int64(2), find.Lookup("skip").Int64())
+		assert.Equal(t, int64(2), find.Lookup("limit").Int64())
+	})
+
+	mt.Run("skip total", func(mt *mtest.T) {
+		ns := mt.DB.Name() + ".users"
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, users...))
+
+		dest := make([]mongoPaginationTestUser, 0)
+		res, err := newMongoPaginationTestDB(mt).FindPagination(&dest, "users", bson.M{}, &models.PageOptions{Page: 1, Limit: 2, SkipTotal: true})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), res.Count)
+		assert.Nil(t, res.Total)
+		assert.Len(t, dest, 2)
+		assert.Nil(t, getMongoTestCommand(mt, "aggregate"))
+	})
+
+	mt.Run("without page options", func(mt *mtest.T) {
+		ns := mt.DB.Name() + ".users"
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: int32(2)}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, users...),
+		)
+
+		dest := make([]mongoPaginationTestUser, 0)
+		res, err := newMongoPaginationTestDB(mt).FindPagination(&dest, "users", bson.M{}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, &models.PageResponse{Total: utils.ToPointer(int64(2)), Count: 2}, res)
+	})
+}
+
+func TestMongoDB_FindAggregatePagination(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	users := []bson.D{
+		{{Key: "_id", Value: "u3"}, {Key: "name", Value: "jim"}},
+		{{Key: "_id", Value: "u4"}, {Key: "name", Value: "joe"}},
+	}
+	pipeline := []bson.M{{"$match": bson.M{"name": bson.M{"$regex": "^j"}}}}
+
+	mt.Run("page", func(mt *mtest.T) {
+		ns := mt.DB.Name() + ".users"
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_count", Value: int64(5)}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, users...),
+		)
+
+		dest := make([]mongoPaginationTestUser, 0)
+		res, err := newMongoPaginationTestDB(mt).FindAggregatePagination(&dest, "users", pipeline, &models.PageOptions{Page: 2, Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []mongoPaginationTestUser{{ID: "u3", Name: "jim"}, {ID: "u4", Name: "joe"}}, dest)
+		assert.Equal(t, &models.PageResponse{Total: utils.ToPointer(int64(5)), Limit: 2, Count: 2, Page: 2}, res)
+
+		count := getMongoTestCommand(mt, "aggregate")
+		require.NotNil(t, count)
+		stages, err := count.Lookup("pipeline").Array().Values()
+		require.NoError(t, err)
+		require.Len(t, stages, 2)
+		assert.Equal(t, "_count", stages[1].Document().Lookup("$count").StringValue())
+
+		page := getMongoTestCommand(mt, "aggregate")
+		require.NotNil(t, page)
+		stages, err = page.Lookup("pipeline").Array().Values()
+		require.NoError(t, err)
+		require.Len(t, stages, 3)
+		assert.Equal(t, int64(2), stages[1].Document().Lookup("$skip").Int64())
+		assert.Equal(t, int64(2), stages[2].Document().Lookup("$limit").Int64())
+	})
+
+	mt.Run("no documents", func(mt *mtest.T) {
+		ns := mt.DB.Name() + ".users"
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch),
+		)
+
+		dest := make([]mongoPaginationTestUser, 0)
+		res, err := newMongoPaginationTestDB(mt).FindAggregatePagination(&dest, "users", pipeline, &models.PageOptions{Page: 1, Limit: 2})
+		require.NoError(t, err)
+		assert.Empty(t, dest)
+		assert.Equal(t, &models.PageResponse{Total: utils.ToPointer(int64(0)), Limit: 2, Page: 1}, res)
+	})
+
+	mt.Run("pipeline type", func(mt *mtest.T) {
+		dest := make([]mongoPaginationTestUser, 0)
+		_, err := newMongoPaginationTestDB(mt).FindAggregatePagination(&dest, "users", bson.A{}, &models.PageOptions{Page: 1, Limit: 2})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,65 @@
+//go:build integration
+
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Transactions need a replica set, a local single-node one can be started with
+// docker run -d -p 27017:27017 mongo:6 --replSet rs0 && docker exec <container> mongosh --eval "rs.initiate()"
+// then run with DB_MONGO_HOST=localhost DB_MONGO_PORT=27017 DB_MONGO_NAME=test go test -tags integration -run TestMongoDB_WithTransaction .
+func TestMongoDB_WithTransaction(t *testing.T) {
+	env := NewEnv()
+	db, err := NewDatabaseMongo(env.Config()).Connect()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	coll := "core_transaction_test"
+	_ = db.Drop(coll)
+	_, err = db.Create(coll, bson.M{"name": "seed"})
+	assert.NoError(t, err)
+
+	errRollback := errors.New("rollback")
+	err = db.WithTransaction(func(tx IMongoDB) error {
+		_, err := tx.Create(coll, bson.M{"name": "order"})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.DeleteMany(coll, bson.M{"name": "seed"})
+		if err != nil {
+			return err
+		}
+
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+
+	count, err := db.Count(coll, bson.M{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	err = db.WithTransaction(func(tx IMongoDB) error {
+		_, err := tx.Create(coll, bson.M{"name": "order"})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.UpdateOne(coll, bson.M{"name": "seed"}, bson.M{"$set": bson.M{"name": "ledger"}})
+		return err
+	})
+	assert.NoError(t, err)
+
+	count, err = db.Count(coll, bson.M{"name": bson.M{"$in": []string{"order", "ledger"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	_ = db.Drop(coll)
+}
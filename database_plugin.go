@@ -0,0 +1,131 @@
+package core
+
+import (
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	databaseMetricsStartKey = "core:metrics:start"
+	databaseTracingSpanKey  = "core:tracing:span"
+)
+
+type databaseMetricsPlugin struct{}
+
+// NewDatabaseMetricsPlugin return a gorm plugin that record the queries to the metrics of the statement's context,
+// it is registered by Database.Connect and can be used with any other *gorm.DB
+func NewDatabaseMetricsPlugin() gorm.Plugin {
+	return &databaseMetricsPlugin{}
+}
+
+func (p databaseMetricsPlugin) Name() string {
+	return "core:metrics"
+}
+
+func (p databaseMetricsPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("core:metrics:before_create", p.before),
+		cb.Create().After("gorm:create").Register("core:metrics:after_create", p.after("create")),
+		cb.Query().Before("gorm:query").Register("core:metrics:before_query", p.before),
+		cb.Query().After("gorm:query").Register("core:metrics:after_query", p.after("query")),
+		cb.Update().Before("gorm:update").Register("core:metrics:before_update", p.before),
+		cb.Update().After("gorm:update").Register("core:metrics:after_update", p.after("update")),
+		cb.Delete().Before("gorm:delete").Register("core:metrics:before_delete", p.before),
+		cb.Delete().After("gorm:delete").Register("core:metrics:after_delete", p.after("delete")),
+		cb.Row().Before("gorm:row").Register("core:metrics:before_row", p.before),
+		cb.Row().After("gorm:row").Register("core:metrics:after_row", p.after("row")),
+		cb.Raw().Before("gorm:raw").Register("core:metrics:before_raw", p.before),
+		cb.Raw().After("gorm:raw").Register("core:metrics:after_raw", p.after("raw")),
+	)
+}
+
+func (p databaseMetricsPlugin) before(db *gorm.DB) {
+	db.InstanceSet(databaseMetricsStartKey, time.Now())
+}
+
+func (p databaseMetricsPlugin) after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		metrics := MetricsFromContext(db.Statement.Context)
+		if metrics == nil {
+			return
+		}
+
+		start, ok := db.InstanceGet(databaseMetricsStartKey)
+		if !ok {
+			return
+		}
+
+		err := db.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+
+		metrics.ObserveDBQuery(db.Dialector.Name(), operation, time.Since(start.(time.Time)), err)
+	}
+}
+
+type databaseTracingPlugin struct{}
+
+// NewDatabaseTracingPlugin return a gorm plugin that create a span of every query as a child of the statement's context,
+// it is registered by Database.Connect and can be used with any other *gorm.DB
+func NewDatabaseTracingPlugin() gorm.Plugin {
+	return &databaseTracingPlugin{}
+}
+
+func (p databaseTracingPlugin) Name() string {
+	return "core:tracing"
+}
+
+func (p databaseTracingPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("core:tracing:before_create", p.before("create")),
+		cb.Create().After("gorm:create").Register("core:tracing:after_create", p.after),
+		cb.Query().Before("gorm:query").Register("core:tracing:before_query", p.before("query")),
+		cb.Query().After("gorm:query").Register("core:tracing:after_query", p.after),
+		cb.Update().Before("gorm:update").Register("core:tracing:before_update", p.before("update")),
+		cb.Update().After("gorm:update").Register("core:tracing:after_update", p.after),
+		cb.Delete().Before("gorm:delete").Register("core:tracing:before_delete", p.before("delete")),
+		cb.Delete().After("gorm:delete").Register("core:tracing:after_delete", p.after),
+		cb.Row().Before("gorm:row").Register("core:tracing:before_row", p.before("row")),
+		cb.Row().After("gorm:row").Register("core:tracing:after_row", p.after),
+		cb.Raw().Before("gorm:raw").Register("core:tracing:before_raw", p.before("raw")),
+		cb.Raw().After("gorm:raw").Register("core:tracing:after_raw", p.after),
+	)
+}
+
+func (p databaseTracingPlugin) before(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement.Context == nil || !trace.SpanContextFromContext(db.Statement.Context).IsValid() {
+			return
+		}
+
+		_, span := Tracer().Start(db.Statement.Context, "gorm "+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+			attribute.String("db.system", db.Dialector.Name()),
+			attribute.String("db.operation", operation),
+			attribute.String("db.sql.table", db.Statement.Table),
+		))
+		db.InstanceSet(databaseTracingSpanKey, span)
+	}
+}
+
+func (p databaseTracingPlugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(databaseTracingSpanKey)
+	if !ok {
+		return
+	}
+
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+
+	span := v.(trace.Span)
+	span.SetAttributes(attribute.String("db.statement", db.Statement.SQL.String()))
+	endSpan(span, err)
+}
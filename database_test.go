@@ -0,0 +1,44 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Leakageonthelamp/go-leakage-core/models"
+	"github.com/Leakageonthelamp/go-leakage-core/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaginate(t *testing.T) {
+	db := newTestSQLite(t)
+	require.NoError(t, db.AutoMigrate(&cursorTestUser{}))
+	require.NoError(t, db.Create(&[]cursorTestUser{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 3, Name: "c"}}).Error)
+
+	tests := []struct {
+		name      string
+		skipTotal bool
+		wantTotal *int64
+	}{
+		{name: "total", wantTotal: utils.ToPointer(int64(3))},
+		{name: "skip total", skipTotal: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := make([]cursorTestUser, 0)
+			res, err := Paginate(db, &items, &models.PageOptions{Page: 2, Limit: 2, OrderBy: []string{"id"}, SkipTotal: tt.skipTotal})
+			require.NoError(t, err)
+			assert.Equal(t, &models.PageResponse{Total: tt.wantTotal, Limit: 2, Count: 1, Page: 2}, res)
+			assert.Equal(t, []int64{3}, getCursorTestUserIDs(items))
+
+			b, err := json.Marshal(models.NewPagination(items, res))
+			require.NoError(t, err)
+			if tt.skipTotal {
+				assert.NotContains(t, string(b), `"total"`)
+			} else {
+				assert.Contains(t, string(b), `"total":3`)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
@@ -29,12 +30,20 @@ type ENVConfig struct {
 	LogHost  string `mapstructure:"log_host"`
 	LogPort  string `mapstructure:"log_port"`
 
-	Host    string `mapstructure:"host"`
-	ENV     string `mapstructure:"env"`
-	Service string `mapstructure:"service"`
+	Host       string `mapstructure:"host"`
+	HealthHost string `mapstructure:"health_host"`
+	ENV        string `mapstructure:"env"`
+	Service    string `mapstructure:"service"`
+
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	ShutdownDelay   time.Duration `mapstructure:"shutdown_delay"`
 
 	SentryDSN string `mapstructure:"sentry_dsn"`
 
+	TraceExporter string `mapstructure:"trace_exporter"`
+	TraceEndpoint string `mapstructure:"trace_endpoint"`
+	TraceInsecure bool   `mapstructure:"trace_insecure"`
+
 	DBDriver   string `mapstructure:"db_driver"`
 	DBHost     string `mapstructure:"db_host"`
 	DBName     string `mapstructure:"db_name"`
@@ -103,8 +112,9 @@ func NewENVPath(path string) IENV {
 	envKeys := []string{
 		"LOG_HOST",
 		"LOG_PORT",
-		"HOST", "ENV", "SERVICE",
+		"HOST", "HEALTH_HOST", "ENV", "SERVICE", "SHUTDOWN_TIMEOUT", "SHUTDOWN_DELAY",
 		"SENTRY_DSN",
+		"TRACE_EXPORTER", "TRACE_ENDPOINT", "TRACE_INSECURE",
 		"DB_DRIVER", "DB_HOST", "DB_HOST", "DB_NAME", "DB_USER", "DB_PASSWORD", "DB_PORT",
 		"DB_MONGO_HOST", "DB_MONGO_NAME", "DB_MONGO_USERNAME", "DB_MONGO_PASSWORD", "DB_MONGO_PORT",
 		"MQ_HOST", "MQ_USER", "MQ_PASSWORD", "MQ_PORT",
@@ -0,0 +1,266 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	HealthStatusUp   = "up"
+	HealthStatusDown = "down"
+
+	healthCheckTimeout = 5 * time.Second
+)
+
+// HealthComponent is the status of a backend, the error of a failed check is logged instead of being returned
+// as it can contain the hosts and users of the backend
+type HealthComponent struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+type HealthResponse struct {
+	Status     string             `json:"status"`
+	Components []*HealthComponent `json:"components,omitempty"`
+}
+
+type IHealthChecker interface {
+	AddCheck(name string, check func(ctx context.Context) error)
+	Check(ctx context.Context) *HealthResponse
+	Ready() bool
+	SetReady(ready bool)
+	LivenessHandler(w http.ResponseWriter, r *http.Request)
+	HealthHandler(w http.ResponseWriter, r *http.Request)
+	ReadinessHandler(w http.ResponseWriter, r *http.Request)
+}
+
+type healthCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+type HealthChecker struct {
+	mu     sync.RWMutex
+	checks []healthCheck
+	ready  atomic.Bool
+}
+
+// NewHealthChecker return a health checker that pings every backend configured in options
+func NewHealthChecker(options *ContextOptions) IHealthChecker {
+	h := &HealthChecker{}
+	h.ready.Store(true)
+
+	if options.DB != nil {
+		h.AddCheck("db", pingSQL(options.DB))
+	}
+
+	for name, db := range options.DBS {
+		h.AddCheck("dbs."+name, pingSQL(db))
+	}
+
+	if options.MongoDB != nil {
+		h.AddCheck("mongodb", pingMongo(options.MongoDB))
+	}
+
+	for name, db := range options.MongoDBS {
+		h.AddCheck("mongodbs."+name, pingMongo(db))
+	}
+
+	if options.Cache != nil {
+		h.AddCheck("cache", pingCache(options.Cache))
+	}
+
+	for name, cache := range options.Caches {
+		h.AddCheck("caches."+name, pingCache(cache))
+	}
+
+	if options.MQ != nil {
+		h.AddCheck("mq", pingMQ(options.MQ))
+	}
+
+	return h
+}
+
+// AddCheck add a component to the health check, check must return an error when the component is not healthy
+func (h *HealthChecker) AddCheck(name string, check func(ctx context.Context) error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// the slice is copied as Check may be reading the current one
+	checks := append(append([]healthCheck{}, h.checks...), healthCheck{name: name, check: check})
+	sort.SliceStable(checks, func(i, j int) bool {
+		return checks[i].name < checks[j].name
+	})
+	h.checks = checks
+}
+
+// Check run every check concurrently, the status is down when any component is down
+func (h *HealthChecker) Check(ctx context.Context) *HealthResponse {
+	h.mu.RLock()
+	checks := h.checks
+	h.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	res := &HealthResponse{Status: HealthStatusUp, Components: make([]*HealthComponent, len(checks))}
+	wg := sync.WaitGroup{}
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c healthCheck) {
+			defer wg.Done()
+			res.Components[i] = runHealthCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, c := range res.Components {
+		if c.Status != HealthStatusUp {
+			res.Status = HealthStatusDown
+		}
+	}
+
+	return res
+}
+
+func (h *HealthChecker) Ready() bool {
+	return h.ready.Load()
+}
+
+// SetReady set the readiness of the service, it's set to false when the service is shutting down
+func (h *HealthChecker) SetReady(ready bool) {
+	h.ready.Store(ready)
+}
+
+// LivenessHandler always return up while the process is able to serve requests
+func (h *HealthChecker) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealthResponse(w, &HealthResponse{Status: HealthStatusUp})
+}
+
+// HealthHandler return the status of every component
+func (h *HealthChecker) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealthResponse(w, h.Check(r.Context()))
+}
+
+// ReadinessHandler return the status of every component, it's down without checking when the service is not ready
+func (h *HealthChecker) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.Ready() {
+		writeHealthResponse(w, &HealthResponse{Status: HealthStatusDown, Components: []*HealthComponent{{
+			Name:   "readiness",
+			Status: HealthStatusDown,
+		}}})
+		return
+	}
+
+	writeHealthResponse(w, h.Check(r.Context()))
+}
+
+// NewHealthServer return an HTTP server that serves /healthz, /readyz, /livez and /metrics when metrics is not nil,
+// it's used by the contexts without an HTTP server
+func NewHealthServer(checker IHealthChecker, metrics IMetrics, host string) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", checker.HealthHandler)
+	mux.HandleFunc("/readyz", checker.ReadinessHandler)
+	mux.HandleFunc("/livez", checker.LivenessHandler)
+	if metrics != nil {
+		mux.Handle("/metrics", metrics.Handler())
+	}
+
+	return &http.Server{Addr: host, Handler: mux, ReadHeaderTimeout: healthCheckTimeout}
+}
+
+// startHealthServer serve the health endpoints in background when host is set
+func startHealthServer(checker IHealthChecker, metrics IMetrics, host string) *http.Server {
+	if host == "" {
+		return nil
+	}
+
+	server := NewHealthServer(checker, metrics, host)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			NewLoggerSimple().Error(err)
+		}
+	}()
+
+	return server
+}
+
+func runHealthCheck(ctx context.Context, c healthCheck) (component *HealthComponent) {
+	component = &HealthComponent{Name: c.name, Status: HealthStatusUp}
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			component.Status = HealthStatusDown
+			NewLoggerSimple().Error(fmt.Errorf("health check %s panic: %v", c.name, r))
+		}
+		component.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	}()
+
+	if err := c.check(ctx); err != nil {
+		component.Status = HealthStatusDown
+		NewLoggerSimple().Warn(fmt.Sprintf("health check %s is down: %v", c.name, err))
+	}
+
+	return component
+}
+
+func writeHealthResponse(w http.ResponseWriter, res *HealthResponse) {
+	status := http.StatusOK
+	if res.Status != HealthStatusUp {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func pingSQL(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+func pingMongo(db IMongoDB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return db.WithContext(ctx).Ping()
+	}
+}
+
+func pingCache(cache ICache) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return cache.WithContext(ctx).Ping()
+	}
+}
+
+// pingMQ ping the broker in background as opening a channel doesn't take a context, the check returns once ctx is done
+func pingMQ(mq IMQ) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan error, 1)
+		go func() {
+			done <- mq.WithContext(ctx).Ping()
+		}()
+
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
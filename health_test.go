@@ -0,0 +1,117 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// healthTestMQ is a broker whose Ping blocks until the context is done
+type healthTestMQ struct {
+	IMQ
+	ctx context.Context
+}
+
+func (m *healthTestMQ) WithContext(ctx context.Context) IMQ {
+	return &healthTestMQ{ctx: ctx}
+}
+
+func (m *healthTestMQ) Ping() error {
+	<-m.ctx.Done()
+	return m.ctx.Err()
+}
+
+func doHealthTestRequest(t *testing.T, handler http.HandlerFunc) (int, string, *HealthResponse) {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	res := &HealthResponse{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), res))
+	return rec.Code, rec.Body.String(), res
+}
+
+func TestHealthChecker_Handlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     map[string]error
+		notReady   bool
+		wantHealth int
+		wantReady  int
+		wantStatus map[string]string
+	}{
+		{
+			name:       "healthy",
+			checks:     map[string]error{"db": nil, "cache": nil},
+			wantHealth: http.StatusOK,
+			wantReady:  http.StatusOK,
+			wantStatus: map[string]string{"cache": HealthStatusUp, "db": HealthStatusUp},
+		},
+		{
+			name:       "unhealthy",
+			checks:     map[string]error{"db": errors.New("dial tcp postgres://admin@10.0.0.5:5432: connection refused"), "cache": nil},
+			wantHealth: http.StatusServiceUnavailable,
+			wantReady:  http.StatusServiceUnavailable,
+			wantStatus: map[string]string{"cache": HealthStatusUp, "db": HealthStatusDown},
+		},
+		{
+			name:       "not ready",
+			checks:     map[string]error{"db": nil},
+			notReady:   true,
+			wantHealth: http.StatusOK,
+			wantReady:  http.StatusServiceUnavailable,
+			wantStatus: map[string]string{"readiness": HealthStatusDown},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewHealthChecker(&ContextOptions{})
+			for name, err := range tt.checks {
+				err := err
+				checker.AddCheck(name, func(ctx context.Context) error {
+					return err
+				})
+			}
+			checker.SetReady(!tt.notReady)
+
+			code, _, _ := doHealthTestRequest(t, checker.LivenessHandler)
+			assert.Equal(t, http.StatusOK, code)
+
+			code, body, _ := doHealthTestRequest(t, checker.HealthHandler)
+			assert.Equal(t, tt.wantHealth, code)
+			assert.NotContains(t, body, "postgres")
+
+			code, body, res := doHealthTestRequest(t, checker.ReadinessHandler)
+			assert.Equal(t, tt.wantReady, code)
+			assert.NotContains(t, body, "postgres")
+
+			status := map[string]string{}
+			for _, c := range res.Components {
+				status[c.Name] = c.Status
+			}
+			assert.Equal(t, tt.wantStatus, status)
+		})
+	}
+}
+
+func TestHealthChecker_MQTimeout(t *testing.T) {
+	checker := NewHealthChecker(&ContextOptions{MQ: &healthTestMQ{}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	res := checker.Check(ctx)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, HealthStatusDown, res.Status)
+	require.Len(t, res.Components, 1)
+	assert.Equal(t, "mq", res.Components[0].Name)
+	assert.Equal(t, HealthStatusDown, res.Components[0].Status)
+}
@@ -2,6 +2,8 @@ package core
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -43,7 +45,10 @@ type HandlerFunc func(IHTTPContext) error
 
 type HTTPContextOptions struct {
 	RateLimit      *middleware.RateLimiterMemoryStoreConfig
+	RateLimitStore IRateLimiterStore // share the rate limit between instances, the in-memory store is used when nil
 	AllowOrigins   []string
+	ProblemJSON    bool           // render errors as RFC 7807 application/problem+json
+	HealthChecker  IHealthChecker // checker of /healthz and /readyz, default checks every backend of ContextOptions
 	AllowHeaders   []string
 	ContextOptions *ContextOptions
 }
@@ -52,7 +57,37 @@ func NewHTTPContext(ctx echo.Context, options *HTTPContextOptions) IHTTPContext
 	ctxOptions := options.ContextOptions
 	ctxOptions.contextType = consts.HTTP
 
-	return &HTTPContext{Context: ctx, logger: nil, IContext: NewContext(ctxOptions)}
+	return &HTTPContext{Context: ctx, logger: nil, IContext: NewContext(ctxOptions).WithContext(ctx.Request().Context())}
+}
+
+// WithContext return a copy of the context with ctx as the context of the underlying request,
+// the request of the original context is left untouched
+func (c *HTTPContext) WithContext(ctx context.Context) IContext {
+	echoCtx := &requestContext{Context: c.Context}
+	echoCtx.SetRequest(c.Request().WithContext(ctx))
+
+	return &HTTPContext{Context: echoCtx, logger: nil, IContext: c.IContext.WithContext(ctx)}
+}
+
+// requestContext is an echo.Context that owns its request, the params, store and response are shared with the wrapped context
+type requestContext struct {
+	echo.Context
+	request *http.Request
+}
+
+func (c *requestContext) Request() *http.Request {
+	return c.request
+}
+
+func (c *requestContext) SetRequest(r *http.Request) {
+	c.request = r
+}
+
+// Transaction run fn in a database transaction, txCtx is an IHTTPContext of the same request
+func (c *HTTPContext) Transaction(fn func(txCtx IContext) error, opts ...*sql.TxOptions) IError {
+	return c.IContext.Transaction(func(txCtx IContext) error {
+		return fn(&HTTPContext{Context: c.Context, logger: nil, IContext: txCtx})
+	}, opts...)
 }
 
 func (c *HTTPContext) WithSaveCache(data interface{}, key string, duration time.Duration) interface{} {
@@ -98,6 +133,7 @@ func (c *HTTPContext) GetPageOptions() *models.PageOptions {
 		Limit:   limit,
 		Page:    page,
 		OrderBy: c.genOrderBy(c.QueryParam("order_by")),
+		Cursor:  c.QueryParam("cursor"),
 	}
 }
 
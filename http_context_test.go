@@ -0,0 +1,55 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestHTTPServer return an echo server with the Core middleware, the context has a cache connected to a miniredis
+func newTestHTTPServer(t *testing.T) (*echo.Echo, ICache, *miniredis.Miniredis) {
+	cache, mr := newTestCache(t)
+
+	e := echo.New()
+	e.Use(Core(&HTTPContextOptions{ContextOptions: &ContextOptions{ENV: NewEnv(), Cache: cache}}))
+
+	return e, cache, mr
+}
+
+func doTestRequest(e *echo.Echo, method string, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+type httpContextTestKey struct{}
+
+func TestHTTPContextWithContext(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/users?q=john", nil)
+	echoCtx := e.NewContext(req, httptest.NewRecorder())
+	echoCtx.SetParamNames("id")
+	echoCtx.SetParamValues("1")
+
+	c := NewHTTPContext(echoCtx, &HTTPContextOptions{ContextOptions: &ContextOptions{ENV: NewEnv()}})
+	derived := c.WithContext(context.WithValue(context.Background(), httpContextTestKey{}, "derived")).(IHTTPContext)
+
+	// the original request keeps its context
+	assert.Same(t, req, c.Request())
+	assert.Nil(t, c.GetContext().Value(httpContextTestKey{}))
+
+	assert.Equal(t, "derived", derived.Request().Context().Value(httpContextTestKey{}))
+	assert.Equal(t, "derived", derived.GetContext().Value(httpContextTestKey{}))
+	assert.Equal(t, "john", derived.QueryParam("q"))
+	assert.Equal(t, "1", derived.Param("id"))
+}
@@ -1,14 +1,50 @@
 package core
 
 import (
+	"net/http"
+
 	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func Core(options *HTTPContextOptions) func(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			req := c.Request()
+			// the path of an unmatched request is not used as the span name, it would be unbounded by the 404 and scan traffic
+			route := c.Path()
+			if route == "" {
+				route = "unknown"
+			}
+
+			// continue the trace of the caller, the span is the parent of every span created by the handler
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			ctx, span := Tracer().Start(ctx, req.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+				attribute.String("http.request.method", req.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", req.URL.Path),
+				attribute.String("client.address", c.RealIP()),
+			))
+			defer span.End()
+
+			c.SetRequest(req.WithContext(ctx))
 			cc := NewHTTPContext(c, options)
-			return next(cc)
+			err := next(cc)
+
+			status := getResponseStatus(c, err)
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			if status >= http.StatusInternalServerError {
+				if err != nil {
+					span.RecordError(err)
+				}
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+
+			return err
 		}
 	}
 }
@@ -1,8 +1,13 @@
 package core
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -48,19 +53,119 @@ func HTTPMiddlewareRecoverWithConfig(env IENV, config middleware.RecoverConfig)
 	}
 }
 
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+type HTTPErrorHandlerConfig struct {
+	ProblemJSON bool // render errors as RFC 7807 application/problem+json
+}
+
 func HTTPMiddlewareHandleError(env IENV) echo.HTTPErrorHandler {
+	return HTTPMiddlewareHandleErrorWithConfig(env, HTTPErrorHandlerConfig{})
+}
+
+// HTTPMiddlewareHandleErrorWithConfig render IError, *echo.HTTPError and validation errors with their own status and body,
+// other errors are rendered as internal server error. The original error is only added as detail in dev
+func HTTPMiddlewareHandleErrorWithConfig(env IENV, config HTTPErrorHandlerConfig) echo.HTTPErrorHandler {
 	return func(err error, c echo.Context) {
-		errMessage := "Internal server error"
-		if env.IsDev() {
-			errMessage = err.Error()
+		if c.Response().Committed {
+			return
 		}
 
-		c.JSON(http.StatusInternalServerError, map[string]interface{}{
-			"code":    "INTERNAL_SERVER_ERROR",
-			"message": errMessage,
-		})
+		ierr := toHTTPError(err, c)
+		status := ierr.GetStatus()
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+
+		body := getHTTPErrorBody(ierr)
+		if rid := c.Response().Header().Get(echo.HeaderXRequestID); rid != "" {
+			body["request_id"] = rid
+		}
+
+		if originalErr := ierr.OriginalError(); env.IsDev() && originalErr != nil && originalErr.Error() != ierr.Error() {
+			body["detail"] = ierr.OriginalError().Error()
+		}
+
+		if c.Request().Method == http.MethodHead {
+			_ = c.NoContent(status)
+			return
+		}
+
+		if !config.ProblemJSON {
+			_ = c.JSON(status, body)
+			return
+		}
+
+		body["type"] = "about:blank"
+		body["title"] = http.StatusText(status)
+		body["status"] = status
+		body["instance"] = c.Request().URL.Path
+
+		b, jsonErr := json.Marshal(body)
+		if jsonErr != nil {
+			_ = c.JSON(status, body)
+			return
+		}
+
+		_ = c.Blob(status, MIMEApplicationProblemJSON, b)
 	}
 }
+
+func toHTTPError(err error, c echo.Context) IError {
+	var ierr IError
+	if errors.As(err, &ierr) {
+		return ierr
+	}
+
+	var valErr govalidator.Errors
+	if errors.As(err, &valErr) {
+		return NewValidatorFields(ErrorToJson(valErr))
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		code := strings.ToUpper(strings.ReplaceAll(http.StatusText(httpErr.Code), " ", "_"))
+		if code == "" {
+			code = "HTTP_ERROR"
+		}
+
+		message := httpErr.Message
+		if m, ok := message.(string); !ok || m == "" {
+			message = http.StatusText(httpErr.Code)
+		}
+
+		return Error{Status: httpErr.Code, Code: code, Message: message, originalError: httpErr.Internal}
+	}
+
+	internalErr := Error{
+		Status:        http.StatusInternalServerError,
+		Code:          "INTERNAL_SERVER_ERROR",
+		Message:       "Internal server error",
+		originalError: err,
+	}
+
+	// errors that are not IError have not been logged by NewError yet
+	if cc, ok := c.(IHTTPContext); ok {
+		_ = cc.NewError(err, internalErr)
+	}
+
+	return internalErr
+}
+
+// getHTTPErrorBody return the JSON of the error as a map, so the request id and detail can be added to any error
+func getHTTPErrorBody(ierr IError) map[string]interface{} {
+	body := map[string]interface{}{}
+	b, err := json.Marshal(ierr.JSON())
+	if err == nil && json.Unmarshal(b, &body) == nil && len(body) > 0 {
+		return body
+	}
+
+	return map[string]interface{}{
+		"code":    ierr.GetCode(),
+		"message": ierr.GetMessage(),
+	}
+}
+
 func HTTPMiddlewareHandleNotFound(c echo.Context) error {
 	return c.JSON(http.StatusNotFound, map[string]interface{}{
 		"code":    "URL_NOT_FOUND",
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPMiddlewareHandleErrorWithConfig(t *testing.T) {
+	notFoundErr := Error{Status: http.StatusNotFound, Code: "USER_NOT_FOUND", Message: "user is not found"}
+	validationErr := govalidator.Errors{govalidator.Error{Name: "email", Validator: "email", Err: errors.New("email is invalid")}}
+
+	tests := []struct {
+		name            string
+		problemJSON     bool
+		handler         echo.HandlerFunc
+		wantCode        int
+		wantContentType string
+		wantBody        map[string]interface{}
+	}{
+		{
+			name: "ierror",
+			handler: func(c echo.Context) error {
+				return notFoundErr
+			},
+			wantCode:        http.StatusNotFound,
+			wantContentType: echo.MIMEApplicationJSONCharsetUTF8,
+			wantBody:        map[string]interface{}{"code": "USER_NOT_FOUND", "message": "user is not found"},
+		},
+		{
+			name: "wrapped ierror",
+			handler: func(c echo.Context) error {
+				return fmt.Errorf("find user: %w", notFoundErr)
+			},
+			wantCode:        http.StatusNotFound,
+			wantContentType: echo.MIMEApplicationJSONCharsetUTF8,
+			wantBody:        map[string]interface{}{"code": "USER_NOT_FOUND", "message": "user is not found"},
+		},
+		{
+			name: "echo http error",
+			handler: func(c echo.Context) error {
+				return echo.NewHTTPError(http.StatusMethodNotAllowed)
+			},
+			wantCode:        http.StatusMethodNotAllowed,
+			wantContentType: echo.MIMEApplicationJSONCharsetUTF8,
+			wantBody:        map[string]interface{}{"code": "METHOD_NOT_ALLOWED", "message": "Method Not Allowed"},
+		},
+		{
+			name: "echo http error with message",
+			handler: func(c echo.Context) error {
+				return echo.NewHTTPError(http.StatusBadRequest, "body is too large")
+			},
+			wantCode:        http.StatusBadRequest,
+			wantContentType: echo.MIMEApplicationJSONCharsetUTF8,
+			wantBody:        map[string]interface{}{"code": "BAD_REQUEST", "message": "body is too large"},
+		},
+		{
+			name: "validation errors",
+			handler: func(c echo.Context) error {
+				return fmt.Errorf("validate: %w", validationErr)
+			},
+			wantCode:        http.StatusBadRequest,
+			wantContentType: echo.MIMEApplicationJSONCharsetUTF8,
+			wantBody: map[string]interface{}{
+				"code":    "INVALID_PARAMS",
+				"message": "Invalid parameters",
+				"fields":  map[string]interface{}{"email": map[string]interface{}{"code": "EMAIL", "message": "email is invalid"}},
+			},
+		},
+		{
+			name: "other error",
+			handler: func(c echo.Context) error {
+				return errors.New("connection refused")
+			},
+			wantCode:        http.StatusInternalServerError,
+			wantContentType: echo.MIMEApplicationJSONCharsetUTF8,
+			wantBody:        map[string]interface{}{"code": "INTERNAL_SERVER_ERROR", "message": "Internal server error"},
+		},
+		{
+			name:        "problem json",
+			problemJSON: true,
+			handler: func(c echo.Context) error {
+				c.Response().Header().Set(echo.HeaderXRequestID, "rid-1")
+				return notFoundErr
+			},
+			wantCode:        http.StatusNotFound,
+			wantContentType: MIMEApplicationProblemJSON,
+			wantBody: map[string]interface{}{
+				"code":       "USER_NOT_FOUND",
+				"message":    "user is not found",
+				"request_id": "rid-1",
+				"type":       "about:blank",
+				"title":      "Not Found",
+				"status":     float64(http.StatusNotFound),
+				"instance":   "/users",
+			},
+		},
+		{
+			name: "committed response",
+			handler: func(c echo.Context) error {
+				_ = c.JSON(http.StatusOK, map[string]interface{}{"id": "1"})
+				return notFoundErr
+			},
+			wantCode:        http.StatusOK,
+			wantContentType: echo.MIMEApplicationJSONCharsetUTF8,
+			wantBody:        map[string]interface{}{"id": "1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			e.HTTPErrorHandler = HTTPMiddlewareHandleErrorWithConfig(NewEnv(), HTTPErrorHandlerConfig{ProblemJSON: tt.problemJSON})
+			e.GET("/users", tt.handler)
+
+			rec := doTestRequest(e, http.MethodGet, "/users", nil)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantContentType, rec.Header().Get(echo.HeaderContentType))
+
+			body := map[string]interface{}{}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, tt.wantBody, body)
+		})
+	}
+}
+
+func TestHTTPMiddlewareHandleError_Head(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPMiddlewareHandleError(NewEnv())
+	e.HEAD("/users", func(c echo.Context) error {
+		return Error{Status: http.StatusNotFound, Code: "USER_NOT_FOUND", Message: "user is not found"}
+	})
+
+	rec := doTestRequest(e, http.MethodHead, "/users", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
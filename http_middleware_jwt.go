@@ -0,0 +1,193 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+var (
+	jwtRequiredError = Error{
+		Status:  http.StatusUnauthorized,
+		Code:    "TOKEN_REQUIRED",
+		Message: "token is required"}
+
+	jwtInvalidError = Error{
+		Status:  http.StatusUnauthorized,
+		Code:    "INVALID_TOKEN",
+		Message: "token is invalid"}
+
+	jwtExpiredError = Error{
+		Status:  http.StatusUnauthorized,
+		Code:    "TOKEN_EXPIRED",
+		Message: "token is expired"}
+)
+
+type JWTAuthConfig struct {
+	Skipper             middleware.Skipper
+	Optional            bool          // let requests without token through, invalid tokens are still rejected
+	SigningMethods      []string      // allowed algorithms, default is HS256, RS256 and ES256
+	Secret              []byte        // key of HS256
+	PublicKey           interface{}   // *rsa.PublicKey of RS256 or *ecdsa.PublicKey of ES256
+	JWKSFile            string        // path of a JWKS file, keys are selected by the kid header
+	JWKSURL             string        // url of a JWKS endpoint, keys are selected by the kid header
+	JWKSRefreshInterval time.Duration // default is 1 hour
+	Audience            string
+	Issuer              string
+	Leeway              time.Duration // allowed clock skew of exp and nbf
+	TokenLookup         func(c echo.Context) string
+	ClaimsMapper        func(claims jwt.MapClaims) (*ContextUser, error)
+}
+
+// HTTPMiddlewareJWTAuth verify the bearer token and set the user of the context from its claims, use it on routes that need a user
+func HTTPMiddlewareJWTAuth(config JWTAuthConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+
+	if len(config.SigningMethods) == 0 {
+		config.SigningMethods = []string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}
+	}
+
+	if config.TokenLookup == nil {
+		config.TokenLookup = getBearerToken
+	}
+
+	if config.ClaimsMapper == nil {
+		config.ClaimsMapper = DefaultJWTClaimsMapper
+	}
+
+	var keySet *jwks
+	if config.JWKSFile != "" || config.JWKSURL != "" {
+		keySet = newJWKS(config.JWKSFile, config.JWKSURL, config.JWKSRefreshInterval)
+		if config.JWKSFile != "" {
+			if err := keySet.load(); err != nil {
+				panic(err)
+			}
+		}
+	} else if config.Secret == nil && config.PublicKey == nil {
+		panic("jwt auth needs Secret, PublicKey, JWKSFile or JWKSURL")
+	}
+
+	parser := &jwt.Parser{ValidMethods: config.SigningMethods, SkipClaimsValidation: true}
+	keyFunc := func(t *jwt.Token) (interface{}, error) {
+		if keySet != nil {
+			kid, _ := t.Header["kid"].(string)
+			return keySet.getKey(kid)
+		}
+
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+			if config.Secret == nil {
+				return nil, errors.New("hmac signed token is not allowed")
+			}
+			return config.Secret, nil
+		}
+
+		if config.PublicKey == nil {
+			return nil, errors.New("public key signed token is not allowed")
+		}
+
+		return config.PublicKey, nil
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			cc, ok := c.(IHTTPContext)
+			if !ok {
+				return errors.New("jwt auth needs the Core middleware")
+			}
+
+			raw := config.TokenLookup(c)
+			if raw == "" {
+				if config.Optional {
+					return next(c)
+				}
+
+				return cc.NewError(errors.New("missing token"), jwtRequiredError)
+			}
+
+			claims := jwt.MapClaims{}
+			if _, err := parser.ParseWithClaims(raw, claims, keyFunc); err != nil {
+				return cc.NewError(err, jwtInvalidError)
+			}
+
+			if ierr := verifyJWTClaims(cc, claims, config); ierr != nil {
+				return ierr
+			}
+
+			user, err := config.ClaimsMapper(claims)
+			if err != nil {
+				return cc.NewError(err, jwtInvalidError)
+			}
+
+			cc.SetUser(user)
+			c.Set("jwt", raw)
+			c.Set("jwt_claims", claims)
+
+			return next(c)
+		}
+	}
+}
+
+// HTTPMiddlewareJWTAuthOptional is HTTPMiddlewareJWTAuth that let requests without token through
+func HTTPMiddlewareJWTAuthOptional(config JWTAuthConfig) echo.MiddlewareFunc {
+	config.Optional = true
+	return HTTPMiddlewareJWTAuth(config)
+}
+
+// DefaultJWTClaimsMapper map sub, email, preferred_username and name claims to the user
+func DefaultJWTClaimsMapper(claims jwt.MapClaims) (*ContextUser, error) {
+	user := &ContextUser{}
+	user.ID, _ = claims["sub"].(string)
+	user.Email, _ = claims["email"].(string)
+	user.Name, _ = claims["name"].(string)
+	user.Username, _ = claims["preferred_username"].(string)
+	if user.Username == "" {
+		user.Username, _ = claims["username"].(string)
+	}
+
+	if user.ID == "" {
+		return nil, errors.New("sub claim is required")
+	}
+
+	return user, nil
+}
+
+func verifyJWTClaims(cc IHTTPContext, claims jwt.MapClaims, config JWTAuthConfig) IError {
+	now := time.Now()
+	if !claims.VerifyExpiresAt(now.Add(-config.Leeway).Unix(), false) {
+		return cc.NewError(errors.New("token is expired"), jwtExpiredError)
+	}
+
+	if !claims.VerifyNotBefore(now.Add(config.Leeway).Unix(), false) {
+		return cc.NewError(errors.New("token is not valid yet"), jwtInvalidError)
+	}
+
+	if config.Audience != "" && !claims.VerifyAudience(config.Audience, true) {
+		return cc.NewError(errors.New("invalid audience"), jwtInvalidError)
+	}
+
+	if config.Issuer != "" && !claims.VerifyIssuer(config.Issuer, true) {
+		return cc.NewError(errors.New("invalid issuer"), jwtInvalidError)
+	}
+
+	return nil
+}
+
+func getBearerToken(c echo.Context) string {
+	auth := c.Request().Header.Get(echo.HeaderAuthorization)
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+
+	return ""
+}
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signJWTTest(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	raw, err := token.SignedString(key)
+	require.NoError(t, err)
+	return raw
+}
+
+func newJWTTestServer(t *testing.T, config JWTAuthConfig) *echo.Echo {
+	e, _, _ := newTestHTTPServer(t)
+	e.HTTPErrorHandler = HTTPMiddlewareHandleError(NewEnv())
+	e.GET("/me", func(c echo.Context) error {
+		cc := c.(IHTTPContext)
+		if cc.GetUser() == nil {
+			return c.String(http.StatusOK, "")
+		}
+
+		return c.String(http.StatusOK, cc.GetUser().ID)
+	}, HTTPMiddlewareJWTAuth(config))
+
+	return e
+}
+
+func TestHTTPMiddlewareJWTAuth(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, rsaJWK := newRSATestJWK(t, "rsa")
+	ecKey, ecJWK := newECTestJWK(t, "ec")
+	otherRSAKey, _ := newRSATestJWK(t, "other")
+	server, _, _ := newJWKSTestServer(t, jwk{Kid: "okp", Kty: "OKP", Crv: "Ed25519", X: "AA"}, rsaJWK, ecJWK)
+
+	claims := func(extra jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		config   JWTAuthConfig
+		token    string
+		wantCode int
+		wantUser string
+	}{
+		{
+			name:     "hs256 secret",
+			config:   JWTAuthConfig{Secret: secret},
+			token:    signJWTTest(t, jwt.SigningMethodHS256, secret, "", claims(nil)),
+			wantCode: http.StatusOK,
+			wantUser: "user-1",
+		},
+		{
+			name:     "rs256 public key",
+			config:   JWTAuthConfig{PublicKey: &rsaKey.PublicKey},
+			token:    signJWTTest(t, jwt.SigningMethodRS256, rsaKey, "", claims(nil)),
+			wantCode: http.StatusOK,
+			wantUser: "user-1",
+		},
+		{
+			name:     "rs256 jwks",
+			config:   JWTAuthConfig{JWKSURL: server.URL},
+			token:    signJWTTest(t, jwt.SigningMethodRS256, rsaKey, "rsa", claims(nil)),
+			wantCode: http.StatusOK,
+			wantUser: "user-1",
+		},
+		{
+			name:     "es256 jwks",
+			config:   JWTAuthConfig{JWKSURL: server.URL},
+			token:    signJWTTest(t, jwt.SigningMethodES256, ecKey, "ec", claims(nil)),
+			wantCode: http.StatusOK,
+			wantUser: "user-1",
+		},
+		{
+			name:     "unknown kid",
+			config:   JWTAuthConfig{JWKSURL: server.URL},
+			token:    signJWTTest(t, jwt.SigningMethodRS256, rsaKey, "missing", claims(nil)),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "wrong key",
+			config:   JWTAuthConfig{JWKSURL: server.URL},
+			token:    signJWTTest(t, jwt.SigningMethodRS256, otherRSAKey, "rsa", claims(nil)),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "hs256 not allowed",
+			config:   JWTAuthConfig{PublicKey: &rsaKey.PublicKey},
+			token:    signJWTTest(t, jwt.SigningMethodHS256, secret, "", claims(nil)),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "signing method not allowed",
+			config:   JWTAuthConfig{Secret: secret, SigningMethods: []string{jwt.SigningMethodHS512.Alg()}},
+			token:    signJWTTest(t, jwt.SigningMethodHS256, secret, "", claims(nil)),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "expired",
+			config:   JWTAuthConfig{Secret: secret},
+			token:    signJWTTest(t, jwt.SigningMethodHS256, secret, "", claims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "expired within leeway",
+			config:   JWTAuthConfig{Secret: secret, Leeway: 2 * time.Minute},
+			token:    signJWTTest(t, jwt.SigningMethodHS256, secret, "", claims(jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})),
+			wantCode: http.StatusOK,
+			wantUser: "user-1",
+		},
+		{
+			name:     "wrong audience",
+			config:   JWTAuthConfig{Secret: secret, Audience: "api"},
+			token:    signJWTTest(t, jwt.SigningMethodHS256, secret, "", claims(jwt.MapClaims{"aud": "web"})),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "issuer",
+			config:   JWTAuthConfig{Secret: secret, Issuer: "auth"},
+			token:    signJWTTest(t, jwt.SigningMethodHS256, secret, "", claims(jwt.MapClaims{"iss": "auth"})),
+			wantCode: http.StatusOK,
+			wantUser: "user-1",
+		},
+		{
+			name:     "missing sub",
+			config:   JWTAuthConfig{Secret: secret},
+			token:    signJWTTest(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()}),
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "missing token",
+			config:   JWTAuthConfig{Secret: secret},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "optional without token",
+			config:   JWTAuthConfig{Secret: secret, Optional: true},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "optional with invalid token",
+			config:   JWTAuthConfig{Secret: secret, Optional: true},
+			token:    "invalid",
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newJWTTestServer(t, tt.config)
+
+			header := http.Header{}
+			if tt.token != "" {
+				header.Set(echo.HeaderAuthorization, "Bearer "+tt.token)
+			}
+
+			rec := doTestRequest(e, http.MethodGet, "/me", header)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode == http.StatusOK {
+				assert.Equal(t, tt.wantUser, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHTTPMiddlewareJWTAuth_WithoutCore(t *testing.T) {
+	secret := []byte("secret")
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPMiddlewareHandleError(NewEnv())
+	e.GET("/me", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, HTTPMiddlewareJWTAuth(JWTAuthConfig{Secret: secret}))
+
+	header := http.Header{}
+	header.Set(echo.HeaderAuthorization, "Bearer "+signJWTTest(t, jwt.SigningMethodHS256, secret, "", jwt.MapClaims{"sub": "user-1"}))
+
+	rec := doTestRequest(e, http.MethodGet, "/me", header)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
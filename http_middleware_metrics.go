@@ -0,0 +1,56 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HTTPMiddlewareMetrics record count and latency of the requests by route, method and status to the metrics of the context
+func HTTPMiddlewareMetrics() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			cc, ok := c.(IHTTPContext)
+			if !ok {
+				return next(c)
+			}
+
+			metrics := cc.Metrics()
+			if metrics == nil {
+				return next(c)
+			}
+
+			start := time.Now()
+			err := next(c)
+
+			route := c.Path()
+			if route == "" {
+				route = "unknown"
+			}
+
+			metrics.ObserveHTTPRequest(c.Request().Method, route, getResponseStatus(c, err), time.Since(start))
+			return err
+		}
+	}
+}
+
+// getResponseStatus return the status that is written for err, the error is rendered after the middlewares return
+func getResponseStatus(c echo.Context, err error) int {
+	if err == nil {
+		return c.Response().Status
+	}
+
+	var ierr IError
+	if errors.As(err, &ierr) && ierr.GetStatus() != 0 {
+		return ierr.GetStatus()
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+
+	return http.StatusInternalServerError
+}
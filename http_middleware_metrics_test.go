@@ -0,0 +1,19 @@
+package core
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPMiddlewareMetrics_WithoutCore(t *testing.T) {
+	e := echo.New()
+	e.GET("/users", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, HTTPMiddlewareMetrics())
+
+	rec := doTestRequest(e, http.MethodGet, "/users", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
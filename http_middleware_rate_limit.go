@@ -1,21 +1,53 @@
 package core
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/Leakageonthelamp/go-leakage-core/utils"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
+type RateLimitConfig struct {
+	Skipper             middleware.Skipper // default skips /healthz, /readyz, /livez and /metrics
+	Store               IRateLimiterStore
+	Limit               RateLimit
+	KeyPrefix           string                      // prefix of the keys in the store, default is "rate_limit"
+	PerRoute            bool                        // keep a separate limit for each route
+	IdentifierExtractor func(c IHTTPContext) string // default is the user id, then the X-Api-Key header, then the real ip
+}
+
+// defaultRateLimitRate is the number of requests per second of an IP when the rate of the options is not set
+const defaultRateLimitRate = 10
+
+// HTTPMiddlewareRateLimit limit the requests of each IP, the health and metrics endpoints are not limited
 func HTTPMiddlewareRateLimit(options *HTTPContextOptions) echo.MiddlewareFunc {
-	config := middleware.RateLimiterMemoryStoreConfig{Rate: 10, Burst: 30, ExpiresIn: 3 * time.Minute}
+	config := middleware.RateLimiterMemoryStoreConfig{Rate: defaultRateLimitRate, Burst: 30, ExpiresIn: 3 * time.Minute}
 	if options.RateLimit != nil {
 		config = *options.RateLimit
 	}
 
+	if config.Rate <= 0 {
+		config.Rate = defaultRateLimitRate
+	}
+
+	if options.RateLimitStore != nil {
+		return HTTPMiddlewareRateLimitWithConfig(RateLimitConfig{
+			Skipper: skipOperationalRoutes,
+			Store:   options.RateLimitStore,
+			Limit: RateLimit{
+				Rate:   1,
+				Period: time.Duration(float64(time.Second) / float64(config.Rate)),
+				Burst:  config.Burst,
+			},
+		})
+	}
+
 	return middleware.RateLimiterWithConfig(middleware.RateLimiterConfig{
-		Skipper: middleware.DefaultSkipper,
+		Skipper: skipOperationalRoutes,
 		Store: middleware.NewRateLimiterMemoryStoreWithConfig(
 			config,
 		),
@@ -34,3 +66,89 @@ func HTTPMiddlewareRateLimit(options *HTTPContextOptions) echo.MiddlewareFunc {
 		},
 	})
 }
+
+// HTTPMiddlewareRateLimitWithConfig limit requests with a shared store, use it on a route after the auth middleware to limit per user
+func HTTPMiddlewareRateLimitWithConfig(config RateLimitConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = skipOperationalRoutes
+	}
+
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = "rate_limit"
+	}
+
+	if config.IdentifierExtractor == nil {
+		config.IdentifierExtractor = getRateLimitIdentifier
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			// the identifier needs the core middleware, fallback to the real ip without it
+			cc, ok := c.(IHTTPContext)
+			identifier := "ip:" + c.RealIP()
+			if ok {
+				identifier = config.IdentifierExtractor(cc)
+			}
+
+			key := config.KeyPrefix
+			if config.PerRoute {
+				key += ":" + c.Request().Method + ":" + c.Path()
+			}
+			key += ":" + identifier
+
+			res, err := config.Store.Allow(c.Request().Context(), key, config.Limit)
+			if err != nil {
+				// let the request through when the store is unavailable, it is better than blocking every request
+				if ok {
+					cc.NewError(err, cacheError)
+				}
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			header.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+
+			if !res.Allowed {
+				header.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+				return c.JSON(http.StatusTooManyRequests, map[string]string{
+					"code":    "TOO_MANY_REQUESTS",
+					"message": "Too many requests",
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func getRateLimitIdentifier(c IHTTPContext) string {
+	if user := c.GetUser(); user != nil && user.ID != "" {
+		return "user:" + user.ID
+	}
+
+	if apiKey := c.Request().Header.Get("X-Api-Key"); apiKey != "" {
+		return "key:" + utils.NewSha256(apiKey)
+	}
+
+	return "ip:" + c.RealIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// skipOperationalRoutes skip the health and metrics endpoints, the probes and the scraper must not be limited
+func skipOperationalRoutes(c echo.Context) bool {
+	switch c.Request().URL.Path {
+	case "/healthz", "/readyz", "/livez", "/metrics":
+		return true
+	}
+
+	return false
+}
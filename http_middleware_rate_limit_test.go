@@ -0,0 +1,124 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRateLimitTestServer(t *testing.T, config RateLimitConfig) (*echo.Echo, *miniredis.Miniredis) {
+	e, cache, mr := newTestHTTPServer(t)
+
+	var err error
+	config.Store, err = NewRateLimiterStore(cache)
+	require.NoError(t, err)
+
+	e.GET("/users", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, HTTPMiddlewareRateLimitWithConfig(config))
+	e.GET("/posts", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, HTTPMiddlewareRateLimitWithConfig(config))
+
+	return e, mr
+}
+
+func doRateLimitRequest(e *echo.Echo, path string, apiKey string) *httptest.ResponseRecorder {
+	header := http.Header{}
+	if apiKey != "" {
+		header.Set("X-Api-Key", apiKey)
+	}
+
+	return doTestRequest(e, http.MethodGet, path, header)
+}
+
+func TestHTTPMiddlewareRateLimitWithConfig(t *testing.T) {
+	e, mr := newRateLimitTestServer(t, RateLimitConfig{
+		Limit: RateLimit{Rate: 2, Period: time.Minute},
+	})
+	mr.SetTime(time.Now())
+
+	rec := doRateLimitRequest(e, "/users", "key-a")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Remaining"))
+
+	rec = doRateLimitRequest(e, "/users", "key-a")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "60", rec.Header().Get("X-RateLimit-Reset"))
+
+	rec = doRateLimitRequest(e, "/posts", "key-a")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+
+	// other api keys have their own limit
+	rec = doRateLimitRequest(e, "/users", "key-b")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// one request is allowed again once the emission interval has passed
+	mr.SetTime(time.Now().Add(30 * time.Second))
+	rec = doRateLimitRequest(e, "/users", "key-a")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	rec = doRateLimitRequest(e, "/users", "key-a")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+}
+
+func TestHTTPMiddlewareRateLimitWithConfigPerRoute(t *testing.T) {
+	e, mr := newRateLimitTestServer(t, RateLimitConfig{
+		Limit:    RateLimit{Rate: 1, Period: time.Minute},
+		PerRoute: true,
+	})
+	mr.SetTime(time.Now())
+
+	assert.Equal(t, http.StatusOK, doRateLimitRequest(e, "/users", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, doRateLimitRequest(e, "/users", "").Code)
+	assert.Equal(t, http.StatusOK, doRateLimitRequest(e, "/posts", "").Code)
+}
+
+func TestHTTPMiddlewareRateLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		shared bool
+	}{
+		{name: "memory store"},
+		{name: "shared store", shared: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, cache, mr := newTestHTTPServer(t)
+			mr.SetTime(time.Now())
+
+			// the rate is not set, the default rate is used instead of dividing by zero
+			options := &HTTPContextOptions{RateLimit: &middleware.RateLimiterMemoryStoreConfig{Burst: 1, ExpiresIn: time.Minute}}
+			if tt.shared {
+				store, err := NewRateLimiterStore(cache)
+				require.NoError(t, err)
+				options.RateLimitStore = store
+			}
+
+			e.Use(HTTPMiddlewareRateLimit(options))
+			e.GET("/users", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+			e.GET("/healthz", func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+
+			assert.Equal(t, http.StatusOK, doRateLimitRequest(e, "/users", "").Code)
+			assert.Equal(t, http.StatusTooManyRequests, doRateLimitRequest(e, "/users", "").Code)
+
+			for i := 0; i < 3; i++ {
+				assert.Equal(t, http.StatusOK, doRateLimitRequest(e, "/healthz", "").Code)
+			}
+		})
+	}
+}
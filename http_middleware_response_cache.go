@@ -0,0 +1,364 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Leakageonthelamp/go-leakage-core/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultResponseCacheTTL    = time.Minute
+	defaultResponseCachePrefix = "response_cache"
+
+	headerETag        = "ETag"
+	headerIfNoneMatch = "If-None-Match"
+	headerXCache      = "X-Cache"
+)
+
+type ResponseCacheConfig struct {
+	Skipper      middleware.Skipper
+	TTL          time.Duration                 // default is 1m
+	KeyPrefix    string                        // prefix of the keys and the tags in the cache, default is "response_cache"
+	VaryHeaders  []string                      // request headers that are part of the key, e.g. Accept-Language
+	PerUser      bool                          // the user id is part of the key, use it on a route after the auth middleware
+	KeyGenerator func(c IHTTPContext) string   // default is built from the method, path, sorted query, VaryHeaders and the user
+	Tags         func(c IHTTPContext) []string // tags of the cached response, they are purged by InvalidateResponseCache
+	Statuses     []int                         // statuses of the responses that are cached, default is 200
+}
+
+// responseCacheEntry is a cached response, Header only has the headers set by the handler
+type responseCacheEntry struct {
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	ETag     string      `json:"etag"`
+	StoredAt time.Time   `json:"stored_at"`
+}
+
+// HTTPMiddlewareResponseCache cache the responses of the GET requests of the route in ctx.Cache() with an ETag,
+// a request whose If-None-Match has the ETag gets 304. The request header Cache-Control: no-cache skips the cached response
+// and stores the new one, no-store skips the cache. A response with Set-Cookie or Cache-Control: no-store or private is not cached
+func HTTPMiddlewareResponseCache(config ResponseCacheConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+
+	if config.TTL <= 0 {
+		config.TTL = defaultResponseCacheTTL
+	}
+
+	if config.KeyPrefix == "" {
+		config.KeyPrefix = defaultResponseCachePrefix
+	}
+
+	if len(config.Statuses) == 0 {
+		config.Statuses = []int{http.StatusOK}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			cc, ok := c.(IHTTPContext)
+			if !ok || config.Skipper(c) || c.Request().Method != http.MethodGet || cc.Cache() == nil {
+				return next(c)
+			}
+
+			directives := getCacheControl(c.Request().Header)
+			if directives["no-store"] {
+				return next(c)
+			}
+
+			key := getResponseCacheKey(cc, config)
+			if !directives["no-cache"] {
+				entry := &responseCacheEntry{}
+				err := cc.Cache().GetJSON(entry, key)
+				if err == nil {
+					return writeResponseCacheEntry(c, entry, true)
+				}
+
+				if !errors.Is(err, redis.Nil) {
+					cc.NewError(err, cacheError)
+				}
+			}
+
+			return captureResponseCache(cc, next, key, config)
+		}
+	}
+}
+
+// captureResponseCache buffer the response of the handler, store it and write it with an ETag
+func captureResponseCache(c IHTTPContext, next echo.HandlerFunc, key string, config ResponseCacheConfig) error {
+	// the handler writes to a response of its own, so the hooks and the size of the response are only set by the final write
+	res := c.Response()
+	before := res.Header().Clone()
+	recorder := &responseCacheRecorder{ResponseWriter: res.Writer}
+	c.SetResponse(echo.NewResponse(recorder, c.Echo()))
+	err := next(c)
+	c.SetResponse(res)
+
+	if !recorder.wroteHeader {
+		return err
+	}
+
+	entry := &responseCacheEntry{
+		Status:   recorder.status,
+		Header:   getResponseCacheHeader(before, res.Header()),
+		Body:     recorder.body.Bytes(),
+		StoredAt: time.Now(),
+	}
+
+	if err == nil && isResponseCacheable(res.Header(), entry.Status, config.Statuses) {
+		entry.ETag = res.Header().Get(headerETag)
+		if entry.ETag == "" {
+			entry.ETag = `"` + utils.NewSha256(string(entry.Body))[:32] + `"`
+			entry.Header.Set(headerETag, entry.ETag)
+		}
+
+		storeResponseCacheEntry(c, key, entry, config)
+	}
+
+	return writeResponseCacheEntry(c, entry, false)
+}
+
+func storeResponseCacheEntry(c IHTTPContext, key string, entry *responseCacheEntry, config ResponseCacheConfig) {
+	if err := c.Cache().SetJSON(key, entry, config.TTL); err != nil {
+		c.NewError(err, cacheError)
+		return
+	}
+
+	if config.Tags == nil {
+		return
+	}
+
+	for _, tag := range config.Tags(c) {
+		tagKey := getResponseCacheTagKey(config.KeyPrefix, tag)
+		if err := c.Cache().SAdd(tagKey, key); err != nil {
+			c.NewError(err, cacheError)
+			continue
+		}
+
+		// the tag is kept as long as its latest response
+		if _, err := c.Cache().Expire(tagKey, config.TTL); err != nil {
+			c.NewError(err, cacheError)
+		}
+	}
+}
+
+// writeResponseCacheEntry write the response, it's 304 when If-None-Match of the request has its ETag
+func writeResponseCacheEntry(c echo.Context, entry *responseCacheEntry, hit bool) error {
+	res := c.Response()
+	header := res.Header()
+	for k, v := range entry.Header {
+		header[k] = v
+	}
+
+	if hit {
+		header.Set(headerXCache, "HIT")
+		header.Set("Age", strconv.Itoa(int(time.Since(entry.StoredAt).Seconds())))
+	} else {
+		header.Set(headerXCache, "MISS")
+	}
+
+	if entry.ETag != "" && matchETag(c.Request().Header.Get(headerIfNoneMatch), entry.ETag) {
+		header.Del(echo.HeaderContentLength)
+		return writeResponse(res, http.StatusNotModified, nil)
+	}
+
+	return writeResponse(res, entry.Status, entry.Body)
+}
+
+// writeResponse write through the echo response, so its Before and After hooks run and its size is set
+func writeResponse(res *echo.Response, status int, body []byte) error {
+	res.WriteHeader(status)
+	if len(body) == 0 {
+		return nil
+	}
+
+	_, err := res.Write(body)
+	return err
+}
+
+// InvalidateResponseCache delete the cached responses of the tags that are stored with the default KeyPrefix
+func InvalidateResponseCache(ctx IContext, tags ...string) IError {
+	return InvalidateResponseCacheWithPrefix(ctx, defaultResponseCachePrefix, tags...)
+}
+
+// InvalidateResponseCacheWithPrefix delete the cached responses of the tags that are stored with keyPrefix
+func InvalidateResponseCacheWithPrefix(ctx IContext, keyPrefix string, tags ...string) IError {
+	cache := ctx.Cache()
+	if cache == nil {
+		return nil
+	}
+
+	for _, tag := range tags {
+		tagKey := getResponseCacheTagKey(keyPrefix, tag)
+		keys, err := cache.SMembers(tagKey)
+		if err != nil {
+			return ctx.NewError(err, cacheError)
+		}
+
+		for _, key := range append(keys, tagKey) {
+			if err := cache.Del(key); err != nil {
+				return ctx.NewError(err, cacheError)
+			}
+		}
+	}
+
+	return nil
+}
+
+// HTTPMiddlewareInvalidateResponseCache delete the cached responses of the tags after the handler of a write endpoint succeeds
+func HTTPMiddlewareInvalidateResponseCache(tags func(c IHTTPContext) []string) echo.MiddlewareFunc {
+	return HTTPMiddlewareInvalidateResponseCacheWithPrefix(defaultResponseCachePrefix, tags)
+}
+
+// HTTPMiddlewareInvalidateResponseCacheWithPrefix is HTTPMiddlewareInvalidateResponseCache for the responses cached with keyPrefix
+func HTTPMiddlewareInvalidateResponseCacheWithPrefix(keyPrefix string, tags func(c IHTTPContext) []string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if err := next(c); err != nil {
+				return err
+			}
+
+			cc, ok := c.(IHTTPContext)
+			if !ok || c.Response().Status >= http.StatusBadRequest {
+				return nil
+			}
+
+			InvalidateResponseCacheWithPrefix(cc, keyPrefix, tags(cc)...)
+			return nil
+		}
+	}
+}
+
+// getResponseCacheKey return the key of the request, the varying part is hashed so the key has a fixed length
+func getResponseCacheKey(c IHTTPContext, config ResponseCacheConfig) string {
+	if config.KeyGenerator != nil {
+		return config.KeyPrefix + ":" + config.KeyGenerator(c)
+	}
+
+	req := c.Request()
+	parts := []string{req.Method, req.URL.Path, req.URL.Query().Encode()}
+	for _, name := range config.VaryHeaders {
+		parts = append(parts, name+"="+req.Header.Get(name))
+	}
+
+	if config.PerUser {
+		user := "anonymous"
+		if u := c.GetUser(); u != nil && u.ID != "" {
+			user = u.ID
+		}
+		parts = append(parts, "user="+user)
+	}
+
+	return config.KeyPrefix + ":" + utils.NewSha256(strings.Join(parts, "\n"))
+}
+
+// getResponseCacheHeader return the headers set by the handler, the headers of the other middlewares e.g. X-Request-Id are not cached
+func getResponseCacheHeader(before http.Header, after http.Header) http.Header {
+	header := http.Header{}
+	for k, v := range after {
+		if old, ok := before[k]; ok && strings.Join(old, ",") == strings.Join(v, ",") {
+			continue
+		}
+		header[k] = v
+	}
+
+	return header
+}
+
+func isResponseCacheable(header http.Header, status int, statuses []int) bool {
+	if header.Get("Set-Cookie") != "" {
+		return false
+	}
+
+	directives := getCacheControl(header)
+	if directives["no-store"] || directives["private"] {
+		return false
+	}
+
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getCacheControl return the directives of the Cache-Control header without their values
+func getCacheControl(header http.Header) map[string]bool {
+	directives := map[string]bool{}
+	for _, value := range header.Values(echo.HeaderCacheControl) {
+		for _, directive := range strings.Split(value, ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				directives[strings.ToLower(name)] = true
+			}
+		}
+	}
+
+	return directives
+}
+
+// matchETag match the ETag with If-None-Match, the weak comparison is used as for GET requests
+func matchETag(ifNoneMatch string, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// responseCacheRecorder buffer the response of the handler
+type responseCacheRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseCacheRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+
+	r.status = status
+	r.wroteHeader = true
+}
+
+func (r *responseCacheRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+
+	return r.body.Write(b)
+}
+
+// Flush is a no-op as the response is written once the handler returns
+func (r *responseCacheRecorder) Flush() {}
+
+func (r *responseCacheRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("response cache doesn't support hijacking")
+}
+
+func getResponseCacheTagKey(keyPrefix string, tag string) string {
+	return keyPrefix + ":tag:" + tag
+}
@@ -0,0 +1,154 @@
+package core
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newResponseCacheTestServer(t *testing.T, calls *int32) *echo.Echo {
+	e, _, _ := newTestHTTPServer(t)
+
+	tags := func(c IHTTPContext) []string {
+		return []string{"users"}
+	}
+
+	e.GET("/users", func(c echo.Context) error {
+		atomic.AddInt32(calls, 1)
+		c.Response().Header().Set("X-Total", "1")
+		return c.JSON(http.StatusOK, []string{"john"})
+	}, HTTPMiddlewareResponseCache(ResponseCacheConfig{Tags: tags}))
+	e.GET("/missing", func(c echo.Context) error {
+		atomic.AddInt32(calls, 1)
+		return c.JSON(http.StatusNotFound, nil)
+	}, HTTPMiddlewareResponseCache(ResponseCacheConfig{}))
+	e.POST("/users", func(c echo.Context) error {
+		return c.NoContent(http.StatusCreated)
+	}, HTTPMiddlewareInvalidateResponseCache(tags))
+
+	return e
+}
+
+func TestHTTPMiddlewareResponseCache(t *testing.T) {
+	var calls int32
+	e := newResponseCacheTestServer(t, &calls)
+
+	rec := doTestRequest(e, http.MethodGet, "/users?b=2&a=1", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "MISS", rec.Header().Get(headerXCache))
+	etag := rec.Header().Get(headerETag)
+	require.NotEmpty(t, etag)
+
+	// the query is sorted in the key
+	rec = doTestRequest(e, http.MethodGet, "/users?a=1&b=2", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "HIT", rec.Header().Get(headerXCache))
+	assert.Equal(t, echo.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	assert.Equal(t, "1", rec.Header().Get("X-Total"))
+	assert.Equal(t, etag, rec.Header().Get(headerETag))
+	assert.JSONEq(t, `["john"]`, rec.Body.String())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	rec = doTestRequest(e, http.MethodGet, "/users?a=1&b=2", http.Header{headerIfNoneMatch: {etag}})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	rec = doTestRequest(e, http.MethodGet, "/users?a=1&b=2", http.Header{echo.HeaderCacheControl: {"no-cache"}})
+	assert.Equal(t, "MISS", rec.Header().Get(headerXCache))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	rec = doTestRequest(e, http.MethodPost, "/users", nil)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = doTestRequest(e, http.MethodGet, "/users?a=1&b=2", nil)
+	assert.Equal(t, "MISS", rec.Header().Get(headerXCache))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestHTTPMiddlewareResponseCache_Status(t *testing.T) {
+	var calls int32
+	e := newResponseCacheTestServer(t, &calls)
+
+	for i := 0; i < 2; i++ {
+		rec := doTestRequest(e, http.MethodGet, "/missing", nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Equal(t, "MISS", rec.Header().Get(headerXCache))
+		assert.Empty(t, rec.Header().Get(headerETag))
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestHTTPMiddlewareResponseCache_ResponseHooks(t *testing.T) {
+	var calls int32
+	e := newResponseCacheTestServer(t, &calls)
+
+	// a middleware in front of the cache, e.g. the logger or the metrics, sees the final response
+	var status int
+	var size int64
+	var hooks int
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Response().After(func() {
+				hooks++
+			})
+			err := next(c)
+			status = c.Response().Status
+			size = c.Response().Size
+			return err
+		}
+	})
+
+	for _, cache := range []string{"MISS", "HIT"} {
+		hooks = 0
+		rec := doTestRequest(e, http.MethodGet, "/users", nil)
+		assert.Equal(t, cache, rec.Header().Get(headerXCache))
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, int64(rec.Body.Len()), size)
+		assert.NotZero(t, size)
+		assert.Equal(t, 1, hooks)
+	}
+
+	etag := doTestRequest(e, http.MethodGet, "/users", nil).Header().Get(headerETag)
+	rec := doTestRequest(e, http.MethodGet, "/users", http.Header{headerIfNoneMatch: {etag}})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, http.StatusNotModified, status)
+	assert.Zero(t, size)
+}
+
+func TestHTTPMiddlewareResponseCache_KeyPrefixTags(t *testing.T) {
+	e, _, mr := newTestHTTPServer(t)
+
+	var calls int32
+	tags := func(c IHTTPContext) []string {
+		return []string{"users"}
+	}
+	handler := func(c echo.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return c.JSON(http.StatusOK, []string{"john"})
+	}
+
+	e.GET("/users", handler, HTTPMiddlewareResponseCache(ResponseCacheConfig{KeyPrefix: "users_v1", Tags: tags}))
+	e.GET("/admin/users", handler, HTTPMiddlewareResponseCache(ResponseCacheConfig{KeyPrefix: "admin_v1", Tags: tags}))
+	e.POST("/users", func(c echo.Context) error {
+		return c.NoContent(http.StatusCreated)
+	}, HTTPMiddlewareInvalidateResponseCacheWithPrefix("users_v1", tags))
+
+	doTestRequest(e, http.MethodGet, "/users", nil)
+	doTestRequest(e, http.MethodGet, "/admin/users", nil)
+	assert.True(t, mr.Exists("users_v1:tag:users"))
+	assert.True(t, mr.Exists("admin_v1:tag:users"))
+	assert.False(t, mr.Exists("response_cache:tag:users"))
+
+	// only the responses of the prefix are purged
+	doTestRequest(e, http.MethodPost, "/users", nil)
+	assert.False(t, mr.Exists("users_v1:tag:users"))
+	assert.True(t, mr.Exists("admin_v1:tag:users"))
+
+	assert.Equal(t, "MISS", doTestRequest(e, http.MethodGet, "/users", nil).Header().Get(headerXCache))
+	assert.Equal(t, "HIT", doTestRequest(e, http.MethodGet, "/admin/users", nil).Header().Get(headerXCache))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,205 @@
+package core
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Leakageonthelamp/go-leakage-core/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+var (
+	signatureInvalidError = Error{
+		Status:  http.StatusBadRequest,
+		Code:    "INVALID_SIGNATURE",
+		Message: "Signature is not valid"}
+
+	signatureExpiredError = Error{
+		Status:  http.StatusBadRequest,
+		Code:    "SIGNATURE_EXPIRED",
+		Message: "Signature timestamp is expired"}
+
+	signatureReplayedError = Error{
+		Status:  http.StatusConflict,
+		Code:    "DUPLICATE_REQUEST",
+		Message: "Request has already been processed"}
+
+	signatureKeyError = Error{
+		Status:  http.StatusInternalServerError,
+		Code:    "SIGNATURE_KEY_ERROR",
+		Message: "Unable to resolve the signature key"}
+)
+
+type SignatureKey struct {
+	PublicKey string                  // PEM public key of the caller
+	Algorithm x509.SignatureAlgorithm // default is ECDSAWithSHA256, use SHA256WithRSAPSS etc. for RSA-PSS
+	Secret    []byte                  // shared secret of the caller, the signature is the base64 HMAC-SHA256 of the message and PublicKey is not used
+}
+
+type ISignatureKeyResolver interface {
+	// ResolveKey return the key of keyID, it returns nil when the key is unknown
+	ResolveKey(c IHTTPContext, keyID string) (*SignatureKey, error)
+}
+
+// SignatureKeyResolverFunc is a function that implements ISignatureKeyResolver
+type SignatureKeyResolverFunc func(c IHTTPContext, keyID string) (*SignatureKey, error)
+
+func (f SignatureKeyResolverFunc) ResolveKey(c IHTTPContext, keyID string) (*SignatureKey, error) {
+	return f(c, keyID)
+}
+
+type VerifySignatureConfig struct {
+	Skipper         middleware.Skipper
+	KeyResolver     ISignatureKeyResolver
+	KeyIDHeader     string        // default is "x-key-id"
+	TimestampHeader string        // unix seconds of the request, default is "x-timestamp"
+	NonceHeader     string        // unique value of the request, default is "x-nonce"
+	MaxClockSkew    time.Duration // allowed difference between the timestamp and now, default is 5 minutes
+	NonceKeyPrefix  string        // prefix of the nonce keys in the cache, default is "signature_nonce"
+}
+
+// GetSignatureMessage return the message that is signed by the caller, it is "<timestamp>.<nonce>.<raw body>"
+func GetSignatureMessage(timestamp string, nonce string, body []byte) string {
+	return timestamp + "." + nonce + "." + utils.BytesToString(body)
+}
+
+// HTTPMiddlewareVerifySignature verify the x-signature header of the request against the public key or the secret of the caller,
+// replayed requests are rejected by the timestamp and the nonce which is kept in the cache of the context
+func HTTPMiddlewareVerifySignature(config VerifySignatureConfig) echo.MiddlewareFunc {
+	if config.KeyResolver == nil {
+		panic("verify signature needs KeyResolver")
+	}
+
+	if config.Skipper == nil {
+		config.Skipper = middleware.DefaultSkipper
+	}
+
+	if config.KeyIDHeader == "" {
+		config.KeyIDHeader = "x-key-id"
+	}
+
+	if config.TimestampHeader == "" {
+		config.TimestampHeader = "x-timestamp"
+	}
+
+	if config.NonceHeader == "" {
+		config.NonceHeader = "x-nonce"
+	}
+
+	if config.MaxClockSkew <= 0 {
+		config.MaxClockSkew = 5 * time.Minute
+	}
+
+	if config.NonceKeyPrefix == "" {
+		config.NonceKeyPrefix = "signature_nonce"
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			cc, ok := c.(IHTTPContext)
+			if !ok {
+				return errors.New("verify signature needs the Core middleware")
+			}
+
+			keyID := c.Request().Header.Get(config.KeyIDHeader)
+			timestamp := c.Request().Header.Get(config.TimestampHeader)
+			nonce := c.Request().Header.Get(config.NonceHeader)
+			signature := cc.GetSignature()
+			if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+				return cc.NewError(errors.New("missing signature headers"), signatureInvalidError)
+			}
+
+			unix, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				return cc.NewError(err, signatureInvalidError)
+			}
+
+			if math.Abs(time.Since(time.Unix(unix, 0)).Seconds()) > config.MaxClockSkew.Seconds() {
+				return cc.NewError(errors.New("signature timestamp is out of the allowed clock skew"), signatureExpiredError)
+			}
+
+			key, err := config.KeyResolver.ResolveKey(cc, keyID)
+			if err != nil {
+				return cc.NewError(err, signatureKeyError)
+			}
+
+			if key == nil {
+				return cc.NewError(errors.New("unknown signature key "+keyID), signatureInvalidError)
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return cc.NewError(err, signatureInvalidError)
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			valid, err := verifySignatureKey(key, signature, GetSignatureMessage(timestamp, nonce, body))
+			if err != nil || !valid {
+				if err == nil {
+					err = errors.New("signature does not match")
+				}
+				return cc.NewError(err, signatureInvalidError)
+			}
+
+			// the nonce is kept a bit longer than the window in which the timestamp is accepted
+			cache := cc.Cache()
+			if cache == nil {
+				return cc.NewError(errors.New("verify signature needs a cache to store nonces"), cacheError)
+			}
+
+			stored, err := cache.SetNX(config.NonceKeyPrefix+":"+keyID+":"+nonce, timestamp, 2*config.MaxClockSkew)
+			if err != nil {
+				return cc.NewError(err, cacheError)
+			}
+
+			if !stored {
+				return cc.NewError(errors.New("nonce "+nonce+" has already been used"), signatureReplayedError)
+			}
+
+			c.Set("signature_key_id", keyID)
+
+			return next(c)
+		}
+	}
+}
+
+func verifySignatureKey(key *SignatureKey, signature string, message string) (bool, error) {
+	if key.Secret != nil {
+		return verifyHMACSignature(key.Secret, signature, message)
+	}
+
+	algorithm := key.Algorithm
+	if algorithm == x509.UnknownSignatureAlgorithm {
+		algorithm = x509.ECDSAWithSHA256
+	}
+
+	return utils.VerifySignatureWithOption(key.PublicKey, signature, message, &utils.VerifySignatureOption{
+		Algorithm: algorithm,
+	})
+}
+
+func verifyHMACSignature(secret []byte, signature string, message string) (bool, error) {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, err
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(message))
+
+	return hmac.Equal(sig, mac.Sum(nil)), nil
+}
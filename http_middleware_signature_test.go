@@ -0,0 +1,223 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Leakageonthelamp/go-leakage-core/utils"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type signatureTestRequest struct {
+	keyID     string
+	timestamp time.Time
+	nonce     string
+	body      string
+	signature string
+}
+
+func newSignatureTestServer(t *testing.T, keys map[string]*SignatureKey) *echo.Echo {
+	e, _, _ := newTestHTTPServer(t)
+	e.HTTPErrorHandler = HTTPMiddlewareHandleError(NewEnv())
+	e.POST("/payments", func(c echo.Context) error {
+		body := map[string]interface{}{}
+		if err := c.Bind(&body); err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, body)
+	}, HTTPMiddlewareVerifySignature(VerifySignatureConfig{
+		KeyResolver: SignatureKeyResolverFunc(func(_ IHTTPContext, keyID string) (*SignatureKey, error) {
+			return keys[keyID], nil
+		}),
+	}))
+
+	return e
+}
+
+func doSignatureTestRequest(e *echo.Echo, r signatureTestRequest) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader(r.body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("x-key-id", r.keyID)
+	req.Header.Set("x-timestamp", strconv.FormatInt(r.timestamp.Unix(), 10))
+	req.Header.Set("x-nonce", r.nonce)
+	req.Header.Set("x-signature", r.signature)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func signHMACTest(secret []byte, message string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(message))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestHTTPMiddlewareVerifySignature(t *testing.T) {
+	ecdsaKeyPair, err := utils.GenerateKeyPairWithOption(&utils.GenerateKeyPairOption{Algorithm: x509.ECDSAWithSHA256})
+	require.NoError(t, err)
+	ecdsaKey := ecdsaKeyPair.(*utils.KeyPair)
+
+	rsaKeyPair, err := utils.GenerateKeyPairWithOption(&utils.GenerateKeyPairOption{Algorithm: x509.SHA256WithRSAPSS})
+	require.NoError(t, err)
+	rsaKey := rsaKeyPair.(*utils.RSAKeyPair)
+
+	secret := []byte("partner-secret")
+	keys := map[string]*SignatureKey{
+		"ecdsa": {PublicKey: ecdsaKey.PublicKeyPem},
+		"pss":   {PublicKey: rsaKey.PublicKeyPem, Algorithm: x509.SHA256WithRSAPSS},
+		"hmac":  {Secret: secret},
+	}
+
+	body := `{"amount":10}`
+	sign := func(keyID string, timestamp time.Time, nonce string, body string) string {
+		message := GetSignatureMessage(strconv.FormatInt(timestamp.Unix(), 10), nonce, []byte(body))
+		switch keyID {
+		case "hmac":
+			return signHMACTest(secret, message)
+		case "pss":
+			signature, err := utils.SignMessageWithOption(rsaKey.PrivateKey, message, &utils.SignMessageOption{Algorithm: x509.SHA256WithRSAPSS})
+			require.NoError(t, err)
+			return signature
+		}
+
+		signature, err := utils.SignMessage(ecdsaKey.PrivateKey, message)
+		require.NoError(t, err)
+		return signature
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name     string
+		request  signatureTestRequest
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name:     "ecdsa key",
+			request:  signatureTestRequest{keyID: "ecdsa", timestamp: now, nonce: "n1", body: body, signature: sign("ecdsa", now, "n1", body)},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "rsa-pss key",
+			request:  signatureTestRequest{keyID: "pss", timestamp: now, nonce: "n1", body: body, signature: sign("pss", now, "n1", body)},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "hmac key",
+			request:  signatureTestRequest{keyID: "hmac", timestamp: now, nonce: "n1", body: body, signature: sign("hmac", now, "n1", body)},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "tampered body",
+			request:  signatureTestRequest{keyID: "ecdsa", timestamp: now, nonce: "n1", body: `{"amount":1000}`, signature: sign("ecdsa", now, "n1", body)},
+			wantCode: http.StatusBadRequest,
+			wantErr:  signatureInvalidError.Code,
+		},
+		{
+			name:     "tampered hmac body",
+			request:  signatureTestRequest{keyID: "hmac", timestamp: now, nonce: "n1", body: `{"amount":1000}`, signature: sign("hmac", now, "n1", body)},
+			wantCode: http.StatusBadRequest,
+			wantErr:  signatureInvalidError.Code,
+		},
+		{
+			name:     "signed by another key",
+			request:  signatureTestRequest{keyID: "pss", timestamp: now, nonce: "n1", body: body, signature: sign("ecdsa", now, "n1", body)},
+			wantCode: http.StatusBadRequest,
+			wantErr:  signatureInvalidError.Code,
+		},
+		{
+			name:     "timestamp before the window",
+			request:  signatureTestRequest{keyID: "ecdsa", timestamp: now.Add(-10 * time.Minute), nonce: "n1", body: body, signature: sign("ecdsa", now.Add(-10*time.Minute), "n1", body)},
+			wantCode: http.StatusBadRequest,
+			wantErr:  signatureExpiredError.Code,
+		},
+		{
+			name:     "timestamp after the window",
+			request:  signatureTestRequest{keyID: "ecdsa", timestamp: now.Add(10 * time.Minute), nonce: "n1", body: body, signature: sign("ecdsa", now.Add(10*time.Minute), "n1", body)},
+			wantCode: http.StatusBadRequest,
+			wantErr:  signatureExpiredError.Code,
+		},
+		{
+			name:     "unknown key id",
+			request:  signatureTestRequest{keyID: "unknown", timestamp: now, nonce: "n1", body: body, signature: sign("ecdsa", now, "n1", body)},
+			wantCode: http.StatusBadRequest,
+			wantErr:  signatureInvalidError.Code,
+		},
+		{
+			name:     "missing signature",
+			request:  signatureTestRequest{keyID: "ecdsa", timestamp: now, nonce: "n1", body: body},
+			wantCode: http.StatusBadRequest,
+			wantErr:  signatureInvalidError.Code,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newSignatureTestServer(t, keys)
+
+			rec := doSignatureTestRequest(e, tt.request)
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantErr != "" {
+				assert.Contains(t, rec.Body.String(), tt.wantErr)
+				return
+			}
+
+			// the handler can read the body again
+			assert.JSONEq(t, tt.request.body, rec.Body.String())
+		})
+	}
+}
+
+func TestHTTPMiddlewareVerifySignature_ReplayedNonce(t *testing.T) {
+	secret := []byte("partner-secret")
+	e := newSignatureTestServer(t, map[string]*SignatureKey{"hmac": {Secret: secret}})
+
+	now := time.Now()
+	body := `{"amount":10}`
+	request := signatureTestRequest{
+		keyID:     "hmac",
+		timestamp: now,
+		nonce:     "n1",
+		body:      body,
+		signature: signHMACTest(secret, GetSignatureMessage(strconv.FormatInt(now.Unix(), 10), "n1", []byte(body))),
+	}
+
+	rec := doSignatureTestRequest(e, request)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = doSignatureTestRequest(e, request)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), signatureReplayedError.Code)
+
+	request.nonce = "n2"
+	request.signature = signHMACTest(secret, GetSignatureMessage(strconv.FormatInt(now.Unix(), 10), "n2", []byte(body)))
+	rec = doSignatureTestRequest(e, request)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHTTPMiddlewareVerifySignature_WithoutCore(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPMiddlewareHandleError(NewEnv())
+	e.POST("/payments", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}, HTTPMiddlewareVerifySignature(VerifySignatureConfig{
+		KeyResolver: SignatureKeyResolverFunc(func(_ IHTTPContext, _ string) (*SignatureKey, error) {
+			return nil, nil
+		}),
+	}))
+
+	rec := doSignatureTestRequest(e, signatureTestRequest{keyID: "hmac", timestamp: time.Now(), nonce: "n1", body: "{}", signature: "c2lnbmF0dXJl"})
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
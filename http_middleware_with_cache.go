@@ -8,6 +8,9 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// HTTPMiddlewareFromCache return the JSON of key as 200 when it's cached by WithSaveCache.
+//
+// Deprecated: use HTTPMiddlewareResponseCache, it caches the whole response of the handler
 func HTTPMiddlewareFromCache(key func(IHTTPContext) string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
@@ -4,8 +4,6 @@ import (
 	"context"
 	"fmt"
 	"net/http"
-	"os"
-	"os/signal"
 	"time"
 
 	sentryecho "github.com/getsentry/sentry-go/echo"
@@ -17,6 +15,12 @@ func NewHTTPServer(options *HTTPContextOptions) *echo.Echo {
 	e := echo.New()
 	e.Use(Core(options))
 
+	// Record metrics and serve them on /metrics when metrics are configured
+	if metrics := options.ContextOptions.Metrics; metrics != nil {
+		e.Use(HTTPMiddlewareMetrics())
+		e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+	}
+
 	if options.ContextOptions.ENV.Config().SentryDSN != "" {
 		e.Use(sentryecho.New(sentryecho.Options{
 			Repanic: true,
@@ -54,9 +58,20 @@ func NewHTTPServer(options *HTTPContextOptions) *echo.Echo {
 	e.Use(HTTPMiddlewareRateLimit(options))
 
 	// Set the custom error handler and not found handler
-	e.HTTPErrorHandler = HTTPMiddlewareHandleError(options.ContextOptions.ENV)
+	e.HTTPErrorHandler = HTTPMiddlewareHandleErrorWithConfig(options.ContextOptions.ENV, HTTPErrorHandlerConfig{
+		ProblemJSON: options.ProblemJSON,
+	})
 	echo.NotFoundHandler = HTTPMiddlewareHandleNotFound
 
+	// Register health endpoints, the service is not ready anymore once the server is shutting down
+	if options.HealthChecker == nil {
+		options.HealthChecker = NewHealthChecker(options.ContextOptions)
+	}
+	RegisterHealthRoutes(e, options.HealthChecker)
+	e.Server.RegisterOnShutdown(func() {
+		options.HealthChecker.SetReady(false)
+	})
+
 	// Apply additional secure middleware
 	e.Use(middleware.Secure())
 
@@ -69,33 +84,49 @@ func NewHTTPServer(options *HTTPContextOptions) *echo.Echo {
 	return e
 }
 
-func StartHTTPServer(e *echo.Echo, env IENV) {
-	if env.Config().ENV == "dev" {
-		// Start server in development mode
-		e.Logger.Fatal(e.Start(env.Config().Host))
-	} else {
-		// Start server in production mode
-
-		// Start server in a goroutine to allow for graceful shutdown
-		go func() {
-			if err := e.Start(env.Config().Host); err != nil && err != http.ErrServerClosed {
-				e.Logger.Fatal("shutting down the server")
-			}
-		}()
-
-		// Wait for interrupt signal to gracefully shutdown the server with a timeout of 10 seconds.
-		// Use a buffered channel to avoid missing signals as recommended for signal.Notify
-		quit := make(chan os.Signal, 1)
-		signal.Notify(quit, os.Interrupt)
-		<-quit
-
-		// Create a context with a timeout of 10 seconds for graceful shutdown
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		// Shutdown the server with the given context
-		if err := e.Shutdown(ctx); err != nil {
+// RegisterHealthRoutes register /healthz, /readyz and /livez to e
+func RegisterHealthRoutes(e *echo.Echo, checker IHealthChecker) {
+	e.GET("/healthz", echo.WrapHandler(http.HandlerFunc(checker.HealthHandler)))
+	e.GET("/readyz", echo.WrapHandler(http.HandlerFunc(checker.ReadinessHandler)))
+	e.GET("/livez", echo.WrapHandler(http.HandlerFunc(checker.LivenessHandler)))
+}
+
+// StartHTTPServer serve e until SIGINT or SIGTERM. On shutdown the service is not ready anymore
+// and the in-flight requests are waited for up to SHUTDOWN_TIMEOUT. When the options given to NewHTTPServer are passed,
+// the readiness goes down SHUTDOWN_DELAY before the server stops accepting connections and the connections of the context are closed at the end
+func StartHTTPServer(e *echo.Echo, env IENV, options ...*HTTPContextOptions) {
+	// Start server in a goroutine to allow for graceful shutdown
+	go func() {
+		if err := e.Start(env.Config().Host); err != nil && err != http.ErrServerClosed {
 			e.Logger.Fatal(err)
 		}
+	}()
+
+	ctx, stop := NotifyShutdown()
+	defer stop()
+	<-ctx.Done()
+
+	fmt.Println(fmt.Sprintf("HTTP Service: %s is shutting down", env.Config().Service))
+	for _, o := range options {
+		if o.HealthChecker != nil {
+			o.HealthChecker.SetReady(false)
+		}
+	}
+
+	// keep serving while the load balancers see the readiness go down
+	if delay := getShutdownDelay(env); delay > 0 && len(options) > 0 {
+		time.Sleep(delay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), getShutdownTimeout(env))
+	defer cancel()
+
+	// Shutdown the server with the given context
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		e.Logger.Error(err)
+	}
+
+	for _, o := range options {
+		CloseContextOptions(o.ContextOptions)
 	}
 }
@@ -0,0 +1,235 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// jwksMinRefreshInterval limit how often an unknown kid can trigger a refresh of the key set
+const jwksMinRefreshInterval = time.Minute
+
+// jwksFailureBackoff is how long a failed load of the key set is returned before it is loaded again
+const jwksFailureBackoff = 10 * time.Second
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	K   string `json:"k"`
+}
+
+type jwks struct {
+	file            string
+	url             string
+	refreshInterval time.Duration
+	client          *http.Client
+
+	loadMu    sync.Mutex
+	mu        sync.RWMutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+	failedAt  time.Time
+	failure   error
+}
+
+func newJWKS(file string, url string, refreshInterval time.Duration) *jwks {
+	if refreshInterval <= 0 {
+		refreshInterval = time.Hour
+	}
+
+	return &jwks{
+		file:            file,
+		url:             url,
+		refreshInterval: refreshInterval,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		keys:            map[string]interface{}{},
+	}
+}
+
+// getKey return the public key of kid, the key set is loaded again when it is stale or kid is unknown
+func (j *jwks) getKey(kid string) (interface{}, error) {
+	j.mu.RLock()
+	key, ok := j.keys[kid]
+	fetchedAt := j.fetchedAt
+	j.mu.RUnlock()
+
+	stale := time.Since(fetchedAt) > j.refreshInterval
+	if ok && !stale {
+		return key, nil
+	}
+
+	if stale || time.Since(fetchedAt) > jwksMinRefreshInterval {
+		if err := j.refresh(fetchedAt); err != nil && !ok {
+			return nil, err
+		}
+	}
+
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	key, ok = j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("jwks: key %q not found", kid)
+	}
+
+	return key, nil
+}
+
+// refresh load the key set unless another request has loaded it since fetchedAt,
+// the error of a failed load is returned without loading again until jwksFailureBackoff has passed
+func (j *jwks) refresh(fetchedAt time.Time) error {
+	j.loadMu.Lock()
+	defer j.loadMu.Unlock()
+
+	j.mu.RLock()
+	loaded := j.fetchedAt.After(fetchedAt)
+	failure := j.failure
+	failedAt := j.failedAt
+	j.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+
+	if failure != nil && time.Since(failedAt) < jwksFailureBackoff {
+		return failure
+	}
+
+	err := j.load()
+	if err != nil {
+		j.mu.Lock()
+		j.failure = err
+		j.failedAt = time.Now()
+		j.mu.Unlock()
+	}
+
+	return err
+}
+
+func (j *jwks) load() error {
+	body, err := j.read()
+	if err != nil {
+		return err
+	}
+
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	if err = json.Unmarshal(body, &set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		// a key set can have keys that are not supported, e.g. OKP, they are skipped so the other keys can be used
+		key, err := k.publicKey()
+		if err != nil {
+			NewLoggerSimple().Warn(fmt.Sprintf("jwks: key %q is skipped: %v", k.Kid, err))
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	j.mu.Lock()
+	j.keys = keys
+	j.fetchedAt = time.Now()
+	j.failure = nil
+	j.mu.Unlock()
+
+	return nil
+}
+
+func (j *jwks) read() ([]byte, error) {
+	if j.file != "" {
+		return os.ReadFile(j.file)
+	}
+
+	res, err := j.client.Get(j.url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: unexpected status %d from %s", res.StatusCode, j.url)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("jwks: unsupported curve %q", k.Crv)
+		}
+
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "oct":
+		return base64.RawURLEncoding.DecodeString(k.K)
+	}
+
+	return nil, fmt.Errorf("jwks: unsupported key type %q", k.Kty)
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("jwks: missing key parameter")
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodeJWKInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func newRSATestJWK(t *testing.T, kid string) (*rsa.PrivateKey, jwk) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return key, jwk{
+		Kid: kid,
+		Kty: "RSA",
+		Use: "sig",
+		N:   encodeJWKInt(key.N),
+		E:   encodeJWKInt(big.NewInt(int64(key.E))),
+	}
+}
+
+func newECTestJWK(t *testing.T, kid string) (*ecdsa.PrivateKey, jwk) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	return key, jwk{
+		Kid: kid,
+		Kty: "EC",
+		Crv: "P-256",
+		X:   encodeJWKInt(key.X),
+		Y:   encodeJWKInt(key.Y),
+	}
+}
+
+// newJWKSTestServer serve the keys as a JWKS, the server responds with 500 while failing is set
+func newJWKSTestServer(t *testing.T, keys ...jwk) (*httptest.Server, *atomic.Int32, *atomic.Bool) {
+	requests := &atomic.Int32{}
+	failing := &atomic.Bool{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests, failing
+}
+
+func TestJWKSLoad(t *testing.T) {
+	rsaKey, rsaJWK := newRSATestJWK(t, "rsa")
+	ecKey, ecJWK := newECTestJWK(t, "ec")
+
+	tests := []struct {
+		name    string
+		keys    []jwk
+		kid     string
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "rsa key", keys: []jwk{rsaJWK, ecJWK}, kid: "rsa", want: &rsaKey.PublicKey},
+		{name: "ec key", keys: []jwk{rsaJWK, ecJWK}, kid: "ec", want: &ecKey.PublicKey},
+		{name: "oct key", keys: []jwk{{Kid: "oct", Kty: "oct", K: base64.RawURLEncoding.EncodeToString([]byte("secret"))}}, kid: "oct", want: []byte("secret")},
+		{name: "unsupported key type is skipped", keys: []jwk{{Kid: "okp", Kty: "OKP", Crv: "Ed25519", X: "AA"}, rsaJWK}, kid: "rsa", want: &rsaKey.PublicKey},
+		{name: "unsupported curve is skipped", keys: []jwk{{Kid: "p224", Kty: "EC", Crv: "P-224", X: "AA", Y: "AA"}, ecJWK}, kid: "ec", want: &ecKey.PublicKey},
+		{name: "skipped key is not found", keys: []jwk{{Kid: "okp", Kty: "OKP", Crv: "Ed25519", X: "AA"}, rsaJWK}, kid: "okp", wantErr: true},
+		{name: "encryption key is not used", keys: []jwk{{Kid: "enc", Kty: "RSA", Use: "enc", N: rsaJWK.N, E: rsaJWK.E}}, kid: "enc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _, _ := newJWKSTestServer(t, tt.keys...)
+
+			key, err := newJWKS("", server.URL, 0).getKey(tt.kid)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, key)
+		})
+	}
+}
+
+func TestJWKSFailureBackoff(t *testing.T) {
+	rsaKey, rsaJWK := newRSATestJWK(t, "rsa")
+	server, requests, failing := newJWKSTestServer(t, rsaJWK)
+	failing.Store(true)
+
+	keySet := newJWKS("", server.URL, 0)
+	_, err := keySet.getKey("rsa")
+	assert.Error(t, err)
+
+	// the failure is returned without fetching again during the backoff
+	failing.Store(false)
+	_, err = keySet.getKey("rsa")
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), requests.Load())
+
+	keySet.mu.Lock()
+	keySet.failedAt = time.Now().Add(-jwksFailureBackoff)
+	keySet.mu.Unlock()
+
+	key, err := keySet.getKey("rsa")
+	require.NoError(t, err)
+	assert.Equal(t, &rsaKey.PublicKey, key)
+	assert.Equal(t, int32(2), requests.Load())
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/getsentry/sentry-go"
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ILogger interface {
@@ -125,6 +126,11 @@ func (logger *Logger) getLogFields(fn string, function string, line int, shortMe
 		fields["_user_id"] = logger.ctx.GetUser().ID
 	}
 
+	if spanCtx := trace.SpanContextFromContext(logger.ctx.GetContext()); spanCtx.IsValid() {
+		fields["_trace_id"] = spanCtx.TraceID().String()
+		fields["_span_id"] = spanCtx.SpanID().String()
+	}
+
 	if logger.Type == consts.HTTP {
 		ctx := logger.ctx.(IHTTPContext)
 		fields["_request_id"] = ctx.Get(echo.HeaderXRequestID)
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type IMetrics interface {
+	Registry() *prometheus.Registry
+	Handler() http.Handler
+	Counter(name string, help string, labels ...string) *prometheus.CounterVec
+	Gauge(name string, help string, labels ...string) *prometheus.GaugeVec
+	Histogram(name string, help string, buckets []float64, labels ...string) *prometheus.HistogramVec
+	ObserveHTTPRequest(method string, route string, status int, duration time.Duration)
+	ObserveDBQuery(system string, operation string, duration time.Duration, err error)
+	ObserveCache(operation string, result string)
+	ObserveMQPublish(queue string, err error)
+	ObserveMQConsume(queue string)
+	ObserveMQDuplicate(queue string)
+	ObserveCronjob(job string, duration time.Duration, err error)
+}
+
+const (
+	CacheResultHit   = "hit"
+	CacheResultMiss  = "miss"
+	CacheResultError = "error"
+)
+
+type Metrics struct {
+	namespace  string
+	registry   *prometheus.Registry
+	mu         sync.Mutex
+	collectors map[string]prometheus.Collector
+
+	httpRequests    *prometheus.CounterVec
+	httpDuration    *prometheus.HistogramVec
+	dbDuration      *prometheus.HistogramVec
+	dbErrors        *prometheus.CounterVec
+	cacheOperations *prometheus.CounterVec
+	mqPublished     *prometheus.CounterVec
+	mqConsumed      *prometheus.CounterVec
+	mqDuplicates    *prometheus.CounterVec
+	cronjobDuration *prometheus.HistogramVec
+	cronjobFailures *prometheus.CounterVec
+}
+
+type metricsContextKey struct{}
+
+// NewMetrics return a metrics registry with the built-in metrics of the library, namespace is the prefix of every metric name
+func NewMetrics(namespace string) IMetrics {
+	m := &Metrics{
+		namespace:  namespace,
+		registry:   prometheus.NewRegistry(),
+		collectors: map[string]prometheus.Collector{},
+	}
+
+	m.registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	m.httpRequests = m.Counter("http_requests_total", "Number of HTTP requests.", "method", "route", "status")
+	m.httpDuration = m.Histogram("http_request_duration_seconds", "Duration of HTTP requests.", nil, "method", "route", "status")
+	m.dbDuration = m.Histogram("db_query_duration_seconds", "Duration of database queries.", nil, "system", "operation")
+	m.dbErrors = m.Counter("db_query_errors_total", "Number of failed database queries.", "system", "operation")
+	m.cacheOperations = m.Counter("cache_operations_total", "Number of cache operations by result.", "operation", "result")
+	m.mqPublished = m.Counter("mq_published_total", "Number of published messages.", "queue", "result")
+	m.mqConsumed = m.Counter("mq_consumed_total", "Number of consumed messages.", "queue")
+	m.mqDuplicates = m.Counter("mq_duplicates_total", "Number of skipped duplicate messages.", "queue")
+	m.cronjobDuration = m.Histogram("cronjob_run_duration_seconds", "Duration of cronjob runs.", nil, "job")
+	m.cronjobFailures = m.Counter("cronjob_failures_total", "Number of failed cronjob runs.", "job")
+
+	return m
+}
+
+// ContextWithMetrics return a copy of ctx that carries m, the database, mongo and cache clients record to the metrics of their context
+func ContextWithMetrics(ctx context.Context, m IMetrics) context.Context {
+	return context.WithValue(ctx, metricsContextKey{}, m)
+}
+
+// MetricsFromContext return the metrics of ctx, it returns nil when ctx has no metrics
+func MetricsFromContext(ctx context.Context) IMetrics {
+	if ctx == nil {
+		return nil
+	}
+
+	m, _ := ctx.Value(metricsContextKey{}).(IMetrics)
+	return m
+}
+
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler return the handler of the /metrics exposition endpoint
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Counter return the counter of name, it is registered on the first call and the same counter is returned afterwards
+func (m *Metrics) Counter(name string, help string, labels ...string) *prometheus.CounterVec {
+	return m.getOrRegister(name, func() prometheus.Collector {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: m.namespace, Name: name, Help: help}, labels)
+	}).(*prometheus.CounterVec)
+}
+
+// Gauge return the gauge of name, it is registered on the first call and the same gauge is returned afterwards
+func (m *Metrics) Gauge(name string, help string, labels ...string) *prometheus.GaugeVec {
+	return m.getOrRegister(name, func() prometheus.Collector {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: m.namespace, Name: name, Help: help}, labels)
+	}).(*prometheus.GaugeVec)
+}
+
+// Histogram return the histogram of name, prometheus.DefBuckets is used when buckets is nil
+func (m *Metrics) Histogram(name string, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	return m.getOrRegister(name, func() prometheus.Collector {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: m.namespace, Name: name, Help: help, Buckets: buckets}, labels)
+	}).(*prometheus.HistogramVec)
+}
+
+func (m *Metrics) getOrRegister(name string, newCollector func() prometheus.Collector) prometheus.Collector {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c, ok := m.collectors[name]; ok {
+		return c
+	}
+
+	c := newCollector()
+	m.registry.MustRegister(c)
+	m.collectors[name] = c
+
+	return c
+}
+
+func (m *Metrics) ObserveHTTPRequest(method string, route string, status int, duration time.Duration) {
+	labels := prometheus.Labels{"method": method, "route": route, "status": strconv.Itoa(status)}
+	m.httpRequests.With(labels).Inc()
+	m.httpDuration.With(labels).Observe(duration.Seconds())
+}
+
+func (m *Metrics) ObserveDBQuery(system string, operation string, duration time.Duration, err error) {
+	m.dbDuration.WithLabelValues(system, operation).Observe(duration.Seconds())
+	if err != nil {
+		m.dbErrors.WithLabelValues(system, operation).Inc()
+	}
+}
+
+func (m *Metrics) ObserveCache(operation string, result string) {
+	m.cacheOperations.WithLabelValues(operation, result).Inc()
+}
+
+func (m *Metrics) ObserveMQPublish(queue string, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+
+	m.mqPublished.WithLabelValues(queue, result).Inc()
+}
+
+func (m *Metrics) ObserveMQConsume(queue string) {
+	m.mqConsumed.WithLabelValues(queue).Inc()
+}
+
+func (m *Metrics) ObserveMQDuplicate(queue string) {
+	m.mqDuplicates.WithLabelValues(queue).Inc()
+}
+
+func (m *Metrics) ObserveCronjob(job string, duration time.Duration, err error) {
+	m.cronjobDuration.WithLabelValues(job).Observe(duration.Seconds())
+	if err != nil {
+		m.cronjobFailures.WithLabelValues(job).Inc()
+	}
+}
@@ -2,17 +2,28 @@ package models
 
 type Pagination struct {
 	Page  int64       `json:"page" example:"1"`
-	Total int64       `json:"total" example:"45"`
+	Total *int64      `json:"total,omitempty" example:"45"`
 	Limit int64       `json:"limit" example:"30"`
 	Count int64       `json:"count" example:"30"`
 	Items interface{} `json:"items"`
 }
 
+type CursorPagination struct {
+	Limit      int64       `json:"limit" example:"30"`
+	Count      int64       `json:"count" example:"30"`
+	Total      *int64      `json:"total,omitempty" example:"45"`
+	NextCursor string      `json:"next_cursor" example:"eyJkIjoibmV4dCIsInYiOlsxXX0"`
+	PrevCursor string      `json:"prev_cursor" example:""`
+	Items      interface{} `json:"items"`
+}
+
 type PageOptions struct {
-	Q       string
-	Limit   int64
-	Page    int64
-	OrderBy []string
+	Q         string
+	Limit     int64
+	Page      int64
+	OrderBy   []string
+	Cursor    string
+	SkipTotal bool
 }
 
 func (p *PageOptions) SetOrderDefault(orders ...string) {
@@ -22,7 +33,7 @@ func (p *PageOptions) SetOrderDefault(orders ...string) {
 }
 
 type PageResponse struct {
-	Total   int64
+	Total   *int64
 	Limit   int64
 	Count   int64
 	Page    int64
@@ -30,6 +41,16 @@ type PageResponse struct {
 	OrderBy []string
 }
 
+type CursorPageResponse struct {
+	Total      *int64   `json:"total,omitempty"`
+	Limit      int64    `json:"limit"`
+	Count      int64    `json:"count"`
+	NextCursor string   `json:"next_cursor"`
+	PrevCursor string   `json:"prev_cursor"`
+	Q          string   `json:"q"`
+	OrderBy    []string `json:"order_by"`
+}
+
 func NewPagination(items interface{}, options *PageResponse) *Pagination {
 	m := &Pagination{}
 	if options != nil {
@@ -47,3 +68,22 @@ func NewPagination(items interface{}, options *PageResponse) *Pagination {
 
 	return m
 }
+
+func NewCursorPagination(items interface{}, options *CursorPageResponse) *CursorPagination {
+	m := &CursorPagination{}
+	if options != nil {
+		m.Limit = options.Limit
+		m.Count = options.Count
+		m.Total = options.Total
+		m.NextCursor = options.NextCursor
+		m.PrevCursor = options.PrevCursor
+	}
+
+	if items == nil {
+		m.Items = make([]interface{}, 0)
+	} else {
+		m.Items = items
+	}
+
+	return m
+}
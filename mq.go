@@ -1,14 +1,18 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/Leakageonthelamp/go-leakage-core/utils"
 	"github.com/sirupsen/logrus"
 
-	"github.com/go-errors/errors"
 	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var MQError = Error{
@@ -17,80 +21,189 @@ var MQError = Error{
 	Message: "mq internal error"}
 
 type MQ struct {
-	Host     string
-	User     string
-	Password string
-	Port     string
-	LogLevel logrus.Level
+	Host                string
+	User                string
+	Password            string
+	Port                string
+	LogLevel            logrus.Level
+	ReconnectMinBackoff time.Duration // first delay between the reconnection attempts, default is 500ms
+	ReconnectMaxBackoff time.Duration // max delay between the reconnection attempts, default is 30s
+	PublishTimeout      time.Duration // time a publish waits for the reconnection before ErrMQNotConnected, default is 5s
 }
 
 type MQPublishOptions struct {
-	Exchange     string
-	MessageID    string
-	Durable      bool
-	AutoDelete   bool
-	Exclusive    bool
-	Mandatory    bool
-	Immediate    bool
-	NoWait       bool
-	DeliveryMode uint8
-	Args         amqp.Table
+	Exchange       string // the message is published to the exchange with RoutingKey, the queue of name is not declared
+	RoutingKey     string // routing key of the message when Exchange is set, default is name
+	NoDeclare      bool   // the queue of name is not declared, e.g. it's a reply-to queue
+	MessageID      string
+	CorrelationID  string
+	ReplyTo        string
+	ContentType    string // default is text/plain
+	Durable        bool
+	AutoDelete     bool
+	Exclusive      bool
+	Mandatory      bool
+	Immediate      bool
+	NoWait         bool
+	DeliveryMode   uint8
+	Args           amqp.Table
+	Headers        amqp.Table
+	Confirm        bool          // wait for the broker to confirm the message, ErrMQPublishNacked is returned when it's rejected
+	ConfirmTimeout time.Duration // time to wait for the confirmation, default is 5s
 }
 
+// IMQ is a message broker, NewMQ connects to RabbitMQ and NewMQMemory runs the broker in process
 type IMQ interface {
 	Close()
+	Publish(name string, body []byte, options *MQPublishOptions) error
 	PublishJSON(name string, data interface{}, options *MQPublishOptions) error
-	Consume(ctx IMQContext, name string, onConsume func(message amqp.Delivery), options *MQConsumeOptions)
-	Conn() *amqp.Connection
+	Request(name string, payload interface{}, timeout time.Duration) ([]byte, IError)
+	DeclareExchange(name string, options *MQExchangeOptions) error
+	BindQueue(queue string, exchange string, routingKey string, options *MQBindOptions) error
+	Consume(ctx IMQContext, name string, onConsume func(message Message), options *MQConsumeOptions)
+	ConsumeContext(ctx IMQContext, name string, onConsume func(ctx IMQContext, message Message), options *MQConsumeOptions)
 	ReConnect()
+	Ping() error
+	WithContext(ctx context.Context) IMQ
+}
+
+// IAMQPConnection is implemented by the IMQ of RabbitMQ, e.g. ctx.MQ().(IAMQPConnection).Conn()
+type IAMQPConnection interface {
+	Conn() *amqp.Connection
 }
 
 type mq struct {
-	connection *amqp.Connection
+	connection *mqConnection
 	mq         *MQ
+	ctx        context.Context
 }
 
+// ReConnect dial a new connection when the connection is closed, it's also done in background once the connection is dropped
 func (m mq) ReConnect() {
-	if m.connection.IsClosed() {
-		m.connection, _ = m.mq.ReConnect()
+	if err := m.connection.reconnect(); err != nil {
+		NewLoggerSimple().Error(err)
 	}
 }
 
-func (m mq) PublishJSON(name string, data interface{}, options *MQPublishOptions) error {
-	m.ReConnect()
-	ch, err := m.Conn().Channel()
+// WithContext return a copy of the mq whose published messages carry the trace of ctx and are recorded to its metrics
+func (m mq) WithContext(ctx context.Context) IMQ {
+	m.ctx = ctx
+	return &m
+}
+
+func (m mq) getContext() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+
+	return m.ctx
+}
+
+// Ping check the connection by opening a channel
+func (m mq) Ping() error {
+	conn := m.connection.get()
+	if conn == nil || conn.IsClosed() {
+		return amqp.ErrClosed
+	}
+
+	ch, err := conn.Channel()
 	if err != nil {
 		return err
 	}
-	defer ch.Close()
 
-	q, err := ch.QueueDeclare(
-		name,               // name
-		options.Durable,    // durable
-		options.AutoDelete, // delete when unused
-		options.Exclusive,  // exclusive
-		options.NoWait,     // no-wait
-		options.Args,       // arguments
-	)
+	return ch.Close()
+}
+
+func (m mq) PublishJSON(name string, data interface{}, options *MQPublishOptions) error {
+	return m.Publish(name, []byte(utils.JSONToString(data)), options)
+}
+
+// Publish publish body to the queue of name, the queue is declared with the options first
+func (m mq) Publish(name string, body []byte, options *MQPublishOptions) (err error) {
+	if options == nil {
+		options = &MQPublishOptions{}
+	}
+
+	ctx, span := Tracer().Start(m.getContext(), name+" publish", trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(
+		attribute.String("messaging.system", "rabbitmq"),
+		attribute.String("messaging.destination.name", name),
+	))
+	defer func() {
+		endSpan(span, err)
+		if metrics := MetricsFromContext(ctx); metrics != nil {
+			metrics.ObserveMQPublish(name, err)
+		}
+	}()
+
+	ch, err := m.channel(ctx)
 	if err != nil {
 		return err
 	}
+	defer ch.Close()
+
+	// the message ID is the key of the deduplication of the consumers
+	messageID := options.MessageID
+	if messageID == "" {
+		messageID = utils.GetUUID()
+	}
+
+	routingKey := name
+	if options.Exchange != "" {
+		if options.RoutingKey != "" {
+			routingKey = options.RoutingKey
+		}
+	} else if !options.NoDeclare {
+		q, err := ch.QueueDeclare(
+			name,               // name
+			options.Durable,    // durable
+			options.AutoDelete, // delete when unused
+			options.Exclusive,  // exclusive
+			options.NoWait,     // no-wait
+			options.Args,       // arguments
+		)
+		if err != nil {
+			return err
+		}
+		routingKey = q.Name
+	}
+
+	contentType := options.ContentType
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+
+	var confirm *mqPublishConfirm
+	if options.Confirm {
+		confirm, err = newMQPublishConfirm(ch, options.Mandatory)
+		if err != nil {
+			return err
+		}
+	}
 
 	err = ch.Publish(
 		options.Exchange,  // exchange
-		q.Name,            // routing key
+		routingKey,        // routing key
 		options.Mandatory, // mandatory
 		options.Immediate, // immediate
 		amqp.Publishing{
-			MessageId:    options.MessageID,
-			DeliveryMode: options.DeliveryMode,
-			ContentType:  "text/plain",
-			Body:         []byte(utils.JSONToString(data)),
+			Headers:       injectMQHeaders(ctx, options.Headers),
+			MessageId:     messageID,
+			CorrelationId: options.CorrelationID,
+			ReplyTo:       options.ReplyTo,
+			DeliveryMode:  options.DeliveryMode,
+			ContentType:   contentType,
+			Body:          body,
 		})
 	if err != nil {
 		return err
 	}
 
+	if confirm != nil {
+		if err := confirm.wait(ctx, options.ConfirmTimeout); err != nil {
+			return err
+		}
+	}
+
 	if m.mq.LogLevel == logrus.DebugLevel {
 		fmt.Printf("Publish a message at '%s' channel\n", name)
 	}
@@ -99,49 +212,127 @@ func (m mq) PublishJSON(name string, data interface{}, options *MQPublishOptions
 }
 
 type MQConsumeOptions struct {
-	Durable    bool
-	AutoDelete bool
-	Exclusive  bool
-	NoWait     bool
-	Args       amqp.Table
-	AutoAck    bool
-	NoLocal    bool
-	Consumer   string
-}
-
-func (m mq) Consume(ctx IMQContext, name string, onConsume func(message amqp.Delivery), options *MQConsumeOptions) {
-	m.ReConnect()
-	ch, err := m.Conn().Channel()
+	Durable            bool
+	AutoDelete         bool
+	Exclusive          bool
+	NoWait             bool
+	Args               amqp.Table
+	AutoAck            bool
+	NoLocal            bool
+	Consumer           string
+	Bindings           []MQBinding     // the queue is bound to the exchanges, the bindings are declared again after a reconnection
+	Prefetch           int             // number of unacknowledged messages the consumer receives, default is 1
+	Concurrency        int             // number of workers that handle the messages, default is 1
+	DeadLetterExchange string          // the exchange is declared and the rejected messages of the queue are routed to it
+	DeadLetterQueue    string          // the queue is declared and bound to DeadLetterExchange
+	Dedup              *MQDedupOptions // the duplicates of the processed messages are acknowledged and skipped
+}
+
+func (m mq) Consume(ctx IMQContext, name string, onConsume func(message Message), options *MQConsumeOptions) {
+	m.ConsumeContext(ctx, name, func(_ IMQContext, message Message) {
+		onConsume(message)
+	}, options)
+}
+
+// ConsumeContext consume the messages of the queue, each message is handled with a context that continues the trace of the publisher.
+// The queue is declared and subscribed again once the connection is back after it is dropped.
+// It stops taking new messages when the context of the consumer is done and returns once the in-flight message is handled
+func (m mq) ConsumeContext(ctx IMQContext, name string, onConsume func(ctx IMQContext, message Message), options *MQConsumeOptions) {
+	if options == nil {
+		options = &MQConsumeOptions{}
+	}
+
+	for {
+		conn, err := m.connection.wait(ctx.GetContext())
+		if err != nil {
+			return
+		}
+
+		stopped, err := m.consume(ctx, conn, name, onConsume, options)
+		if stopped {
+			if err != nil {
+				ctx.NewError(err, MQError)
+			}
+			return
+		}
+
+		if err != nil && !conn.IsClosed() {
+			ctx.NewError(err, MQError)
+			return
+		}
+
+		if m.mq.LogLevel == logrus.DebugLevel {
+			fmt.Printf("Consumer of '%s' channel is disconnected, subscribing again\n", name)
+		}
+
+		// the deliveries can be closed with an open connection, e.g. the queue is deleted, wait before subscribing again
+		if !conn.IsClosed() {
+			select {
+			case <-ctx.GetContext().Done():
+				return
+			case <-time.After(defaultMQReconnectMinBackoff):
+			}
+		}
+	}
+}
+
+// consume subscribe to the queue on conn and handle the messages until the deliveries are closed,
+// stopped is true when the consumer is stopped by its context
+func (m mq) consume(ctx IMQContext, conn *amqp.Connection, name string, onConsume func(ctx IMQContext, message Message), options *MQConsumeOptions) (stopped bool, err error) {
+	ch, err := conn.Channel()
 	if err != nil {
-		ctx.NewError(err, MQError)
+		return false, err
 	}
 
 	defer ch.Close()
 
+	args, err := declareMQDeadLetter(ch, options)
+	if err != nil {
+		return false, err
+	}
+
 	q, err := ch.QueueDeclare(
 		name,               // name
 		options.Durable,    // durable
 		options.AutoDelete, // delete when unused
 		options.Exclusive,  // exclusive
 		options.NoWait,     // no-wait
-		options.Args,       // arguments
+		args,               // arguments
 	)
 	if err != nil {
-		ctx.NewError(err, MQError)
+		return false, err
+	}
+
+	for _, binding := range options.Bindings {
+		err = ch.QueueBind(q.Name, binding.RoutingKey, binding.Exchange, options.NoWait, binding.Args)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	prefetch := options.Prefetch
+	if prefetch <= 0 {
+		prefetch = 1
 	}
 
 	err = ch.Qos(
-		1,     // prefetch count
-		0,     // prefetch size
-		false, // global
+		prefetch, // prefetch count
+		0,        // prefetch size
+		false,    // global
 	)
 	if err != nil {
-		ctx.NewError(err, MQError)
+		return false, err
+	}
+
+	// the consumer tag is needed to cancel the consumer on shutdown
+	consumer := options.Consumer
+	if consumer == "" {
+		consumer = fmt.Sprintf("%s-%s", name, utils.GetUUID())
 	}
 
 	msgs, err := ch.Consume(
 		q.Name,            // queue
-		options.Consumer,  // consumer
+		consumer,          // consumer
 		options.AutoAck,   // auto-ack
 		options.Exclusive, // exclusive
 		options.NoLocal,   // no-local
@@ -149,28 +340,83 @@ func (m mq) Consume(ctx IMQContext, name string, onConsume func(message amqp.Del
 		options.Args,      // args
 	)
 	if err != nil {
-		ctx.NewError(err, MQError)
+		return false, err
 	}
 
-	var forever chan struct{}
-	go func() {
-		defer func() {
-			if err := recover(); err != nil {
-				errmsg := errors.New(fmt.Sprintf("%v", err))
-				fmt.Println(errmsg)
-				ctx.NewError(errmsg, MQError)
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	// the handlers are not cancelled by the shutdown of the consumer, they are waited for instead
+	baseCtx := context.WithoutCancel(ctx.GetContext())
+	workers := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for d := range msgs {
+				if m.mq.LogLevel == logrus.DebugLevel {
+					fmt.Println(fmt.Sprintf("Received a message at '%s' channel", name))
+				}
+
+				handleMQMessage(ctx, baseCtx, "rabbitmq", name, newAMQPMessage(d, options.AutoAck), onConsume, options)
 			}
 		}()
+	}
 
-		for d := range msgs {
-			if m.mq.LogLevel == logrus.DebugLevel {
-				fmt.Println(fmt.Sprintf("Received a message at '%s' channel", name))
-			}
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
 
-			onConsume(d)
+	select {
+	case <-ctx.GetContext().Done():
+		// stop taking new messages, the deliveries are closed after the in-flight message is handled.
+		// The channel is closed when the consumer can't be cancelled, the deliveries are closed and the unacknowledged messages are requeued
+		err := ch.Cancel(consumer, false)
+		if err != nil {
+			_ = ch.Close()
 		}
-	}()
-	<-forever
+		<-done
+		return true, err
+	case <-done:
+		return false, nil
+	}
+}
+
+// declareMQDeadLetter declare the dead letter exchange and queue of options,
+// it returns the arguments of the consumed queue that route the rejected messages to the exchange
+func declareMQDeadLetter(ch *amqp.Channel, options *MQConsumeOptions) (amqp.Table, error) {
+	if options.DeadLetterExchange == "" {
+		return options.Args, nil
+	}
+
+	err := ch.ExchangeDeclare(options.DeadLetterExchange, amqp.ExchangeFanout, true, false, false, options.NoWait, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.DeadLetterQueue != "" {
+		_, err = ch.QueueDeclare(options.DeadLetterQueue, true, false, false, options.NoWait, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		err = ch.QueueBind(options.DeadLetterQueue, "", options.DeadLetterExchange, options.NoWait, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	args := amqp.Table{}
+	for k, v := range options.Args {
+		args[k] = v
+	}
+	args["x-dead-letter-exchange"] = options.DeadLetterExchange
+
+	return args, nil
 }
 
 func NewMQ(env *ENVConfig) *MQ {
@@ -194,7 +440,7 @@ func (m *MQ) Connect() (IMQ, error) {
 		return nil, err
 	}
 
-	return &mq{connection: conn, mq: m}, nil
+	return &mq{connection: newMQConnection(m, conn), mq: m, ctx: context.Background()}, nil
 }
 
 // ConnectDB to connect Database
@@ -211,13 +457,15 @@ func (m *MQ) ReConnect() (*amqp.Connection, error) {
 	return conn, nil
 }
 
+// Close close the connection, it's not reconnected anymore
 func (m mq) Close() {
-	err := m.connection.Close()
+	err := m.connection.close()
 	if err != nil {
 		panic(err)
 	}
 }
 
+// Conn return the current connection of IAMQPConnection, it's replaced after a reconnection
 func (m mq) Conn() *amqp.Connection {
-	return m.connection
+	return m.connection.get()
 }
@@ -0,0 +1,207 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	defaultMQReconnectMinBackoff = 500 * time.Millisecond
+	defaultMQReconnectMaxBackoff = 30 * time.Second
+	defaultMQPublishTimeout      = 5 * time.Second
+)
+
+// ErrMQNotConnected is returned when the connection is not back before the timeout or the mq is closed
+var ErrMQNotConnected = errors.New("mq is not connected")
+
+// mqConnection keep the connection of the mq, it reconnects with exponential backoff when the connection is dropped
+type mqConnection struct {
+	mq *MQ
+
+	mu        sync.RWMutex
+	conn      *amqp.Connection
+	connected bool
+	ready     chan struct{} // closed while connected
+	closing   chan struct{}
+	closeOnce sync.Once
+	dialMu    sync.Mutex
+}
+
+func newMQConnection(m *MQ, conn *amqp.Connection) *mqConnection {
+	c := &mqConnection{mq: m, ready: make(chan struct{}), closing: make(chan struct{})}
+	c.setConnection(conn)
+
+	return c
+}
+
+func (c *mqConnection) get() *amqp.Connection {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.conn
+}
+
+// setConnection publish conn, NotifyClose is registered before so a connection that is dropped right away is still noticed
+func (c *mqConnection) setConnection(conn *amqp.Connection) {
+	c.mu.Lock()
+	closed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	c.conn = conn
+	if !c.connected {
+		c.connected = true
+		close(c.ready)
+	}
+	c.mu.Unlock()
+
+	go c.watch(conn, closed)
+}
+
+// watch wait until conn is closed and reconnect when it's not closed by Close
+func (c *mqConnection) watch(conn *amqp.Connection, closed <-chan *amqp.Error) {
+	reason := "the connection is closed"
+	if amqpErr := <-closed; amqpErr != nil {
+		reason = amqpErr.Error()
+	}
+
+	c.disconnected(conn, reason)
+}
+
+// disconnected mark conn as dropped and reconnect in background, it's a no-op when conn is already replaced or the mq is closed
+func (c *mqConnection) disconnected(conn *amqp.Connection, reason string) {
+	select {
+	case <-c.closing:
+		return
+	default:
+	}
+
+	c.mu.Lock()
+	if c.conn != conn || !c.connected {
+		c.mu.Unlock()
+		return
+	}
+	c.connected = false
+	c.ready = make(chan struct{})
+	c.mu.Unlock()
+
+	NewLoggerSimple().Warn(fmt.Sprintf("mq connection is closed: %s, reconnecting", reason))
+	go c.reconnectWithBackoff()
+}
+
+// reconnectWithBackoff dial until the connection is back or the mq is closed
+func (c *mqConnection) reconnectWithBackoff() {
+	backoff := c.mq.ReconnectMinBackoff
+	if backoff <= 0 {
+		backoff = defaultMQReconnectMinBackoff
+	}
+
+	maxBackoff := c.mq.ReconnectMaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMQReconnectMaxBackoff
+	}
+
+	for {
+		err := c.reconnect()
+		if err == nil {
+			return
+		}
+
+		NewLoggerSimple().Warn(fmt.Sprintf("mq reconnection failed: %v, retrying in %v", err, backoff))
+
+		// the jitter spreads the reconnections of the instances when the broker is back
+		select {
+		case <-c.closing:
+			return
+		case <-time.After(backoff + time.Duration(rand.Int63n(int64(backoff)/2+1))):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// reconnect dial a new connection when the current one is closed
+func (c *mqConnection) reconnect() error {
+	c.dialMu.Lock()
+	defer c.dialMu.Unlock()
+
+	select {
+	case <-c.closing:
+		return ErrMQNotConnected
+	default:
+	}
+
+	if conn := c.get(); conn != nil && !conn.IsClosed() {
+		return nil
+	}
+
+	conn, err := c.mq.ReConnect()
+	if err != nil {
+		return err
+	}
+
+	c.setConnection(conn)
+	return nil
+}
+
+// wait return the connection once it's connected, it fails when ctx is done or the mq is closed
+func (c *mqConnection) wait(ctx context.Context) (*amqp.Connection, error) {
+	for {
+		c.mu.RLock()
+		ready := c.ready
+		c.mu.RUnlock()
+
+		select {
+		case <-ready:
+		case <-c.closing:
+			return nil, ErrMQNotConnected
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		conn := c.get()
+		if !conn.IsClosed() {
+			return conn, nil
+		}
+
+		// the connection is dropped but it's not noticed by watch yet, the reconnection is started here and waited for
+		c.disconnected(conn, "the connection is found closed")
+		select {
+		case <-c.closing:
+			return nil, ErrMQNotConnected
+		default:
+		}
+	}
+}
+
+// waitTimeout wait for the connection up to timeout, ErrMQNotConnected is returned on timeout
+func (c *mqConnection) waitTimeout(ctx context.Context, timeout time.Duration) (*amqp.Connection, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	conn, err := c.wait(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, ErrMQNotConnected
+	}
+
+	return conn, err
+}
+
+func (c *mqConnection) close() error {
+	c.closeOnce.Do(func() {
+		close(c.closing)
+	})
+
+	err := c.get().Close()
+	if errors.Is(err, amqp.ErrClosed) {
+		return nil
+	}
+
+	return err
+}
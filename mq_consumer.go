@@ -0,0 +1,156 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	// MQHeaderRetryCount is the header of the number of times the message has been retried
+	MQHeaderRetryCount = "x-retry-count"
+
+	defaultMQMaxRetries = 3
+	defaultMQRetryDelay = 5 * time.Second
+)
+
+var mqMessageInvalidError = Error{
+	Status:  http.StatusBadRequest,
+	Code:    "INVALID_MESSAGE",
+	Message: "message is invalid"}
+
+type MQConsumeJSONOptions struct {
+	MQConsumeOptions
+	MaxRetries int           // retries after the first failure, default is 3 and a negative value disables the retries
+	RetryDelay time.Duration // delay before a failed message is handled again, default is 5s
+}
+
+// ConsumeJSON consume the queue of name and decode every message to T before calling handler.
+// The message is acknowledged when handler returns nil, otherwise it is published to the queue <name>.retry
+// and comes back to the queue after RetryDelay. After MaxRetries, or when the message can't be decoded,
+// it's published to the dead letter exchange <name>.dlx and kept in the queue <name>.dead.
+// Both copies are confirmed by the broker before the message is acknowledged, it's redelivered otherwise.
+// The queue of name is declared with x-dead-letter-exchange, so an existing queue without it can't be consumed
+func ConsumeJSON[T any](ctx IMQContext, name string, handler func(ctx IMQContext, data T) IError, options *MQConsumeJSONOptions) {
+	if options == nil {
+		options = &MQConsumeJSONOptions{}
+	}
+
+	maxRetries := options.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultMQMaxRetries
+	}
+
+	retryDelay := options.RetryDelay
+	if retryDelay <= 0 {
+		retryDelay = defaultMQRetryDelay
+	}
+
+	consumeOptions := options.MQConsumeOptions
+	consumeOptions.AutoAck = false
+	if consumeOptions.DeadLetterExchange == "" {
+		consumeOptions.DeadLetterExchange = name + ".dlx"
+	}
+	if consumeOptions.DeadLetterQueue == "" {
+		consumeOptions.DeadLetterQueue = name + ".dead"
+	}
+
+	// the expired messages of the retry queue are routed back to the queue of name by the default exchange
+	retryOptions := &MQPublishOptions{
+		Durable: consumeOptions.Durable,
+		Args: amqp.Table{
+			"x-message-ttl":             retryDelay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": name,
+		},
+		Confirm: true,
+	}
+
+	// the rejected messages are published to the dead letter exchange instead of being nacked,
+	// the broker doesn't confirm the messages that it dead-letters itself
+	deadLetterOptions := &MQPublishOptions{
+		Exchange:   consumeOptions.DeadLetterExchange,
+		RoutingKey: name,
+		Mandatory:  true,
+		Confirm:    true,
+	}
+
+	ctx.ConsumeContext(name, func(msgCtx IMQContext, message Message) {
+		ierr, retryable := handleJSONMessage(msgCtx, message, handler)
+		if ierr == nil {
+			if err := message.Ack(); err != nil {
+				msgCtx.NewError(err, MQError)
+			}
+			return
+		}
+
+		retries := getMQRetryCount(message.Headers)
+		if !retryable || maxRetries < 0 || retries >= maxRetries {
+			republishMQMessage(msgCtx, message, consumeOptions.DeadLetterExchange, message.Headers, deadLetterOptions)
+			return
+		}
+
+		headers := amqp.Table{}
+		for k, v := range message.Headers {
+			headers[k] = v
+		}
+		headers[MQHeaderRetryCount] = int32(retries + 1)
+
+		republishMQMessage(msgCtx, message, name+".retry", headers, retryOptions)
+	}, &consumeOptions)
+}
+
+// republishMQMessage publish the message to name and acknowledge it once the broker has confirmed the copy,
+// the message is redelivered when it can't be published
+func republishMQMessage(ctx IMQContext, message Message, name string, headers amqp.Table, options *MQPublishOptions) {
+	publishOptions := *options
+	publishOptions.Headers = headers
+	publishOptions.MessageID = message.ID
+	publishOptions.CorrelationID = message.CorrelationID
+	publishOptions.ContentType = message.ContentType
+	publishOptions.DeliveryMode = message.DeliveryMode
+	if err := ctx.MQ().Publish(name, message.Body, &publishOptions); err != nil {
+		ctx.NewError(err, MQError)
+		_ = message.Nack(true)
+		return
+	}
+
+	if err := message.Ack(); err != nil {
+		ctx.NewError(err, MQError)
+	}
+}
+
+// handleJSONMessage decode the message and call handler, retryable is false when the message can't be decoded
+func handleJSONMessage[T any](ctx IMQContext, message Message, handler func(ctx IMQContext, data T) IError) (ierr IError, retryable bool) {
+	var data T
+	if err := json.Unmarshal(message.Body, &data); err != nil {
+		return ctx.NewError(err, mqMessageInvalidError), false
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			ierr = ctx.NewError(fmt.Errorf("%v", r), MQError)
+			retryable = true
+		}
+	}()
+
+	return handler(ctx, data), true
+}
+
+func getMQRetryCount(headers map[string]interface{}) int {
+	switch v := headers[MQHeaderRetryCount].(type) {
+	case int:
+		return v
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	default:
+		return 0
+	}
+}
@@ -1,42 +1,116 @@
 package core
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"sync"
 
 	"github.com/Leakageonthelamp/go-leakage-core/consts"
-	"github.com/streadway/amqp"
 )
 
 type IMQContext interface {
 	IContext
 	AddConsumer(handlerFunc func(ctx IMQContext))
-	Consume(name string, onConsume func(message amqp.Delivery), options *MQConsumeOptions)
+	Consume(name string, onConsume func(message Message), options *MQConsumeOptions)
+	ConsumeContext(name string, onConsume func(ctx IMQContext, message Message), options *MQConsumeOptions)
+	ConsumeRequest(name string, handler MQRequestHandler, options *MQConsumeOptions)
 	Start()
 }
 
 type MQContext struct {
 	IContext
+	health    IHealthChecker
+	options   *ContextOptions
+	stopped   context.Context // done once Start is shutting down, only the consume loops are stopped by it
+	stop      context.CancelFunc
+	consumers *sync.WaitGroup
 }
 
+// Start block the consumer service until SIGINT or SIGTERM, the health and metrics endpoints are served on HEALTH_HOST when it is set.
+// On shutdown the consumers stop taking messages, the in-flight messages are waited for up to SHUTDOWN_TIMEOUT
+// and then the connections of the context are closed
 func (c *MQContext) Start() {
 	fmt.Println(fmt.Sprintf("MQ Consumer Service: %s", c.ENV().Config().Service))
-	select {}
+	server := startHealthServer(c.health, c.Metrics(), c.ENV().Config().HealthHost)
+
+	ctx, stop := NotifyShutdown()
+	defer stop()
+	<-ctx.Done()
+
+	fmt.Println(fmt.Sprintf("MQ Consumer Service: %s is shutting down", c.ENV().Config().Service))
+	c.health.SetReady(false)
+	c.stop()
+	if !waitTimeout(c.consumers.Wait, getShutdownTimeout(c.ENV())) {
+		c.Log().Warn("the in-flight messages are not done before the shutdown timeout")
+	}
+
+	shutdownHealthServer(server)
+	CloseContextOptions(c.options)
+}
+
+func (c *MQContext) with(ctx IContext) *MQContext {
+	return &MQContext{IContext: ctx, health: c.health, options: c.options, stopped: c.stopped, stop: c.stop, consumers: c.consumers}
+}
+
+func (c *MQContext) WithContext(ctx context.Context) IContext {
+	return c.with(c.IContext.WithContext(ctx))
+}
+
+func (c *MQContext) Transaction(fn func(txCtx IContext) error, opts ...*sql.TxOptions) IError {
+	return c.IContext.Transaction(func(txCtx IContext) error {
+		return fn(c.with(txCtx))
+	}, opts...)
 }
 
 func (c *MQContext) AddConsumer(handlerFunc func(ctx IMQContext)) {
 	handlerFunc(c)
 }
 
-func (c *MQContext) Consume(name string, onConsume func(message amqp.Delivery), options *MQConsumeOptions) {
-	go c.MQ().Consume(c, name, onConsume, options)
+func (c *MQContext) Consume(name string, onConsume func(message Message), options *MQConsumeOptions) {
+	c.ConsumeContext(name, func(_ IMQContext, message Message) {
+		onConsume(message)
+	}, options)
+}
+
+// ConsumeContext consume the queue like Consume, ctx of onConsume carries the trace of the publisher of the message
+func (c *MQContext) ConsumeContext(name string, onConsume func(ctx IMQContext, message Message), options *MQConsumeOptions) {
+	c.consumers.Add(1)
+	go func() {
+		defer c.consumers.Done()
+
+		// only the consume loop is cancelled on shutdown, the handlers and the contexts they close over keep running
+		ctx, cancel := context.WithCancel(c.GetContext())
+		defer cancel()
+		stopAfter := context.AfterFunc(c.stopped, cancel)
+		defer stopAfter()
+
+		c.MQ().ConsumeContext(c.with(c.IContext.WithContext(ctx)), name, onConsume, options)
+	}()
 }
 
 type MQContextOptions struct {
 	ContextOptions *ContextOptions
+	HealthChecker  IHealthChecker // default checks every backend of ContextOptions
 }
 
 func NewMQContext(options *MQContextOptions) IMQContext {
 	ctxOptions := options.ContextOptions
 	ctxOptions.contextType = consts.MQ
-	return &MQContext{IContext: NewContext(ctxOptions)}
+
+	health := options.HealthChecker
+	if health == nil {
+		health = NewHealthChecker(ctxOptions)
+	}
+
+	// the consume loops are stopped by Start on shutdown, the context of the handlers is never cancelled
+	stopped, stop := context.WithCancel(context.Background())
+	return &MQContext{
+		IContext:  NewContext(ctxOptions),
+		health:    health,
+		options:   ctxOptions,
+		stopped:   stopped,
+		stop:      stop,
+		consumers: &sync.WaitGroup{},
+	}
 }
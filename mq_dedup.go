@@ -0,0 +1,284 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultMQDedupTTL           = 24 * time.Hour
+	defaultMQDedupProcessingTTL = 5 * time.Minute
+	defaultMQDedupRequeueDelay  = time.Second
+
+	mqDedupStatusProcessing = "processing"
+	mqDedupStatusDone       = "done"
+)
+
+// MQDedupClaim is the result of IMQDedupStore.Claim
+type MQDedupClaim int
+
+const (
+	MQDedupClaimed    MQDedupClaim = iota // the key is claimed by the caller
+	MQDedupProcessing                     // the key is claimed by another handler that is not done yet
+	MQDedupDone                           // the key is processed
+)
+
+// IMQDedupStore keep the keys of the processed messages
+type IMQDedupStore interface {
+	// Claim mark key as processing, it returns MQDedupProcessing or MQDedupDone when key is already claimed
+	Claim(ctx context.Context, key string, ttl time.Duration) (MQDedupClaim, error)
+	// Complete mark key as processed for ttl
+	Complete(ctx context.Context, key string, ttl time.Duration) error
+	// Release remove key, so the message can be processed again
+	Release(ctx context.Context, key string) error
+}
+
+type MQDedupOptions struct {
+	Store         IMQDedupStore
+	KeyExtractor  func(message Message) string // default is GetMQDedupKey, the message is not deduplicated when the key is empty
+	TTL           time.Duration                // time a processed key is kept, default is 24h
+	ProcessingTTL time.Duration                // time a key is claimed by a handler that doesn't finish, default is 5m
+	RequeueDelay  time.Duration                // delay before a message whose key is processing is requeued, default is 1s
+}
+
+// GetMQDedupKey return the message ID with the retry count of ConsumeJSON, so a retried message is not a duplicate
+func GetMQDedupKey(message Message) string {
+	if message.ID == "" {
+		return ""
+	}
+
+	if retries := getMQRetryCount(message.Headers); retries > 0 {
+		return fmt.Sprintf("%s#%d", message.ID, retries)
+	}
+
+	return message.ID
+}
+
+// claimMQMessage claim the message in the dedup store, it returns MQDedupDone when the message is processed
+// and MQDedupProcessing when it's still handled, e.g. by a consumer that crashed before its ack.
+// The claim is completed when the message is acknowledged and released when it's rejected
+func claimMQMessage(ctx IMQContext, name string, message *Message, options *MQDedupOptions) (claim MQDedupClaim, complete func(), release func()) {
+	noop := func() {}
+	if options == nil || options.Store == nil {
+		return MQDedupClaimed, noop, noop
+	}
+
+	extractor := options.KeyExtractor
+	if extractor == nil {
+		extractor = GetMQDedupKey
+	}
+
+	key := extractor(*message)
+	if key == "" {
+		return MQDedupClaimed, noop, noop
+	}
+	key = name + ":" + key
+
+	ttl := options.TTL
+	if ttl <= 0 {
+		ttl = defaultMQDedupTTL
+	}
+
+	processingTTL := options.ProcessingTTL
+	if processingTTL <= 0 {
+		processingTTL = defaultMQDedupProcessingTTL
+	}
+
+	// the store doesn't stop the message from being processed when it fails
+	claim, err := options.Store.Claim(ctx.GetContext(), key, processingTTL)
+	if err != nil {
+		ctx.NewError(err, MQError)
+		return MQDedupClaimed, noop, noop
+	}
+
+	switch claim {
+	case MQDedupDone:
+		ctx.Log().Warn(fmt.Sprintf("Duplicate message '%s' at '%s' channel is skipped", key, name))
+		if metrics := ctx.Metrics(); metrics != nil {
+			metrics.ObserveMQDuplicate(name)
+		}
+		return claim, noop, noop
+	case MQDedupProcessing:
+		ctx.Log().Warn(fmt.Sprintf("Message '%s' at '%s' channel is still processing, it's requeued", key, name))
+		return claim, noop, noop
+	}
+
+	complete = func() {
+		if err := options.Store.Complete(ctx.GetContext(), key, ttl); err != nil {
+			ctx.NewError(err, MQError)
+		}
+	}
+	release = func() {
+		if err := options.Store.Release(ctx.GetContext(), key); err != nil {
+			ctx.NewError(err, MQError)
+		}
+	}
+
+	if message.acknowledger != nil {
+		message.acknowledger = &mqDedupAcknowledger{acknowledger: message.acknowledger, complete: complete, release: release}
+	}
+
+	return MQDedupClaimed, complete, release
+}
+
+// getMQDedupRequeueDelay return the delay before a message whose key is processing is requeued
+func getMQDedupRequeueDelay(options *MQDedupOptions) time.Duration {
+	if options == nil || options.RequeueDelay <= 0 {
+		return defaultMQDedupRequeueDelay
+	}
+
+	return options.RequeueDelay
+}
+
+// mqDedupAcknowledger complete or release the claim of the message once it's acknowledged or rejected
+type mqDedupAcknowledger struct {
+	acknowledger IMessageAcknowledger
+	complete     func()
+	release      func()
+}
+
+func (a *mqDedupAcknowledger) Ack() error {
+	err := a.acknowledger.Ack()
+	if err == nil {
+		a.complete()
+	}
+
+	return err
+}
+
+func (a *mqDedupAcknowledger) Nack(requeue bool) error {
+	a.release()
+	return a.acknowledger.Nack(requeue)
+}
+
+type mqDedupCacheStore struct {
+	cache ICache
+}
+
+// NewMQDedupCacheStore return a dedup store that keeps the keys in cache with their TTL
+func NewMQDedupCacheStore(cache ICache) IMQDedupStore {
+	return &mqDedupCacheStore{cache: cache}
+}
+
+func (s mqDedupCacheStore) Claim(ctx context.Context, key string, ttl time.Duration) (MQDedupClaim, error) {
+	cache := s.cache.WithContext(ctx)
+	ok, err := cache.SetNX(mqDedupCacheKey(key), mqDedupStatusProcessing, ttl)
+	if err != nil || ok {
+		return MQDedupClaimed, err
+	}
+
+	var status string
+	err = cache.Get(&status, mqDedupCacheKey(key))
+	if errors.Is(err, redis.Nil) {
+		// the key is released or expired since SetNX, the message is requeued and claimed again
+		return MQDedupProcessing, nil
+	}
+	if err != nil {
+		return MQDedupClaimed, err
+	}
+
+	return getMQDedupClaim(status), nil
+}
+
+func (s mqDedupCacheStore) Complete(ctx context.Context, key string, ttl time.Duration) error {
+	return s.cache.WithContext(ctx).Set(mqDedupCacheKey(key), mqDedupStatusDone, ttl)
+}
+
+func (s mqDedupCacheStore) Release(ctx context.Context, key string) error {
+	return s.cache.WithContext(ctx).Del(mqDedupCacheKey(key))
+}
+
+func mqDedupCacheKey(key string) string {
+	return "mq:dedup:" + key
+}
+
+// MQProcessedMessage is a key of the SQL dedup store
+type MQProcessedMessage struct {
+	Key       string    `json:"key" gorm:"primaryKey;column:message_key;type:varchar(255)"`
+	Status    string    `json:"status" gorm:"type:varchar(16)"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (MQProcessedMessage) TableName() string {
+	return "mq_processed_messages"
+}
+
+// MigrateMQDedup create or update the table of the SQL dedup store
+func MigrateMQDedup(db *gorm.DB) error {
+	return db.AutoMigrate(&MQProcessedMessage{})
+}
+
+type mqDedupSQLStore struct {
+	db *gorm.DB
+}
+
+// NewMQDedupSQLStore return a dedup store that keeps the keys in the mq_processed_messages table,
+// the expired keys are deleted when they are claimed again or by DeleteExpiredMQDedup
+func NewMQDedupSQLStore(db *gorm.DB) IMQDedupStore {
+	return &mqDedupSQLStore{db: db}
+}
+
+func (s mqDedupSQLStore) Claim(ctx context.Context, key string, ttl time.Duration) (MQDedupClaim, error) {
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+	err := db.Where("message_key = ? AND expires_at < ?", key, now).Delete(&MQProcessedMessage{}).Error
+	if err != nil {
+		return MQDedupClaimed, err
+	}
+
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&MQProcessedMessage{
+		Key:       key,
+		Status:    mqDedupStatusProcessing,
+		ExpiresAt: now.Add(ttl),
+	})
+	if res.Error != nil {
+		return MQDedupClaimed, res.Error
+	}
+
+	if res.RowsAffected == 1 {
+		return MQDedupClaimed, nil
+	}
+
+	processed := &MQProcessedMessage{}
+	err = db.Where("message_key = ?", key).Take(processed).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// the key is released since the insert, the message is requeued and claimed again
+		return MQDedupProcessing, nil
+	}
+	if err != nil {
+		return MQDedupClaimed, err
+	}
+
+	return getMQDedupClaim(processed.Status), nil
+}
+
+func (s mqDedupSQLStore) Complete(ctx context.Context, key string, ttl time.Duration) error {
+	return s.db.WithContext(ctx).Model(&MQProcessedMessage{}).Where("message_key = ?", key).Updates(map[string]interface{}{
+		"status":     mqDedupStatusDone,
+		"expires_at": time.Now().Add(ttl),
+	}).Error
+}
+
+func (s mqDedupSQLStore) Release(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).Where("message_key = ?", key).Delete(&MQProcessedMessage{}).Error
+}
+
+func getMQDedupClaim(status string) MQDedupClaim {
+	if status == mqDedupStatusDone {
+		return MQDedupDone
+	}
+
+	return MQDedupProcessing
+}
+
+// DeleteExpiredMQDedup delete the expired keys of the SQL dedup store, e.g. from a cronjob
+func DeleteExpiredMQDedup(db *gorm.DB) error {
+	return db.Where("expires_at < ?", time.Now()).Delete(&MQProcessedMessage{}).Error
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestSQLite return a database in a file of the temporary directory of the test
+func newTestSQLite(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+	})
+
+	return db
+}
+
+func TestMQDedupStore(t *testing.T) {
+	tests := []struct {
+		name     string
+		newStore func(t *testing.T) (IMQDedupStore, func(d time.Duration))
+	}{
+		{
+			name: "cache",
+			newStore: func(t *testing.T) (IMQDedupStore, func(d time.Duration)) {
+				cache, mr := newTestCache(t)
+				return NewMQDedupCacheStore(cache), mr.FastForward
+			},
+		},
+		{
+			name: "sql",
+			newStore: func(t *testing.T) (IMQDedupStore, func(d time.Duration)) {
+				db := newTestSQLite(t)
+				require.NoError(t, MigrateMQDedup(db))
+				return NewMQDedupSQLStore(db), func(d time.Duration) {
+					// the SQL store compares the expiration with the clock
+					time.Sleep(d)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, forward := tt.newStore(t)
+			ctx := context.Background()
+
+			claim, err := store.Claim(ctx, "orders:1", time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, MQDedupClaimed, claim)
+
+			claim, err = store.Claim(ctx, "orders:1", time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, MQDedupProcessing, claim)
+
+			require.NoError(t, store.Complete(ctx, "orders:1", time.Minute))
+			claim, err = store.Claim(ctx, "orders:1", time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, MQDedupDone, claim)
+
+			// a released key is claimed again
+			claim, err = store.Claim(ctx, "orders:2", time.Minute)
+			require.NoError(t, err)
+			require.Equal(t, MQDedupClaimed, claim)
+			require.NoError(t, store.Release(ctx, "orders:2"))
+			claim, err = store.Claim(ctx, "orders:2", time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, MQDedupClaimed, claim)
+
+			// the claim of a handler that never finished expires
+			claim, err = store.Claim(ctx, "orders:3", 50*time.Millisecond)
+			require.NoError(t, err)
+			require.Equal(t, MQDedupClaimed, claim)
+			forward(60 * time.Millisecond)
+			claim, err = store.Claim(ctx, "orders:3", time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, MQDedupClaimed, claim)
+		})
+	}
+}
+
+func TestMQMemory_Dedup(t *testing.T) {
+	ctx := newMQMemoryTestContext(t)
+	cache, _ := newTestCache(t)
+	store := NewMQDedupCacheStore(cache)
+	dedup := &MQDedupOptions{Store: store, RequeueDelay: 20 * time.Millisecond}
+
+	// message-1 is processed, message-2 is claimed by a consumer that crashed before its ack
+	require.NoError(t, store.Complete(context.Background(), "orders:message-1", time.Minute))
+	claim, err := store.Claim(context.Background(), "orders:message-2", time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, MQDedupClaimed, claim)
+
+	var calls int32
+	received := make(chan Message, 2)
+	ctx.ConsumeContext("orders", func(_ IMQContext, message Message) {
+		atomic.AddInt32(&calls, 1)
+		received <- message
+		assert.NoError(t, message.Ack())
+	}, &MQConsumeOptions{Dedup: dedup})
+
+	for _, id := range []string{"message-1", "message-2"} {
+		id := id
+		require.Eventually(t, func() bool {
+			return ctx.MQ().Publish("orders", []byte(id), &MQPublishOptions{MessageID: id, Mandatory: true}) == nil
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	// message-2 is requeued until its claim is gone, message-1 is acknowledged and skipped
+	time.Sleep(100 * time.Millisecond)
+	assert.Zero(t, atomic.LoadInt32(&calls))
+	require.NoError(t, store.Release(context.Background(), "orders:message-2"))
+
+	message := receiveMQMemoryTest(t, received)
+	assert.Equal(t, "message-2", message.ID)
+	assert.True(t, message.Redelivered)
+
+	claim, err = store.Claim(context.Background(), "orders:message-2", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, MQDedupDone, claim)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
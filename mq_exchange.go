@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const defaultMQConfirmTimeout = 5 * time.Second
+
+var (
+	// ErrMQPublishNacked is returned when the broker rejects a confirmed message
+	ErrMQPublishNacked = errors.New("mq message is not acknowledged by the broker")
+	// ErrMQPublishReturned is returned when a mandatory confirmed message can't be routed to any queue
+	ErrMQPublishReturned = errors.New("mq message is returned by the broker")
+	// ErrMQPublishConfirmTimeout is returned when the confirmation doesn't come before the timeout
+	ErrMQPublishConfirmTimeout = errors.New("mq message confirmation timed out")
+)
+
+type MQExchangeOptions struct {
+	Kind       string // direct, fanout, topic or headers, default is topic
+	Durable    bool
+	AutoDelete bool
+	Internal   bool
+	NoWait     bool
+	Args       amqp.Table
+}
+
+type MQBindOptions struct {
+	NoWait bool
+	Args   amqp.Table
+}
+
+type MQBinding struct {
+	Exchange   string
+	RoutingKey string
+	Args       amqp.Table
+}
+
+// DeclareExchange declare the exchange of name, it's a no-op when the exchange exists with the same options
+func (m mq) DeclareExchange(name string, options *MQExchangeOptions) error {
+	if options == nil {
+		options = &MQExchangeOptions{}
+	}
+
+	kind := options.Kind
+	if kind == "" {
+		kind = amqp.ExchangeTopic
+	}
+
+	ch, err := m.channel(m.getContext())
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	return ch.ExchangeDeclare(name, kind, options.Durable, options.AutoDelete, options.Internal, options.NoWait, options.Args)
+}
+
+// BindQueue bind the queue to the exchange with routingKey, the queue must be declared first.
+// Use MQConsumeOptions.Bindings to bind the queue of a consumer
+func (m mq) BindQueue(queue string, exchange string, routingKey string, options *MQBindOptions) error {
+	if options == nil {
+		options = &MQBindOptions{}
+	}
+
+	ch, err := m.channel(m.getContext())
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	return ch.QueueBind(queue, routingKey, exchange, options.NoWait, options.Args)
+}
+
+// channel open a channel once the connection is available, it waits for the reconnection up to PublishTimeout
+func (m mq) channel(ctx context.Context) (*amqp.Channel, error) {
+	timeout := m.mq.PublishTimeout
+	if timeout <= 0 {
+		timeout = defaultMQPublishTimeout
+	}
+
+	conn, err := m.connection.waitTimeout(ctx, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.Channel()
+}
+
+// mqPublishConfirm wait for the confirmation of the message published on a channel in confirm mode
+type mqPublishConfirm struct {
+	confirms <-chan amqp.Confirmation
+	returns  <-chan amqp.Return
+}
+
+func newMQPublishConfirm(ch *amqp.Channel, mandatory bool) (*mqPublishConfirm, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, err
+	}
+
+	c := &mqPublishConfirm{confirms: ch.NotifyPublish(make(chan amqp.Confirmation, 1))}
+	if mandatory {
+		c.returns = ch.NotifyReturn(make(chan amqp.Return, 1))
+	}
+
+	return c, nil
+}
+
+// wait return nil when the message is acknowledged, the broker sends the return of a message before its confirmation
+func (c *mqPublishConfirm) wait(ctx context.Context, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultMQConfirmTimeout
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	returned := false
+	for {
+		select {
+		case <-c.returns:
+			returned = true
+		case confirm, ok := <-c.confirms:
+			if !ok {
+				return amqp.ErrClosed
+			}
+			if !confirm.Ack {
+				return ErrMQPublishNacked
+			}
+			if returned {
+				return ErrMQPublishReturned
+			}
+
+			// the return is already sent but both channels can be ready at once
+			select {
+			case <-c.returns:
+				return ErrMQPublishReturned
+			default:
+				return nil
+			}
+		case <-timer.C:
+			return ErrMQPublishConfirmTimeout
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
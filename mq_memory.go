@@ -0,0 +1,658 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Leakageonthelamp/go-leakage-core/utils"
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var errMQMessageAcknowledged = errors.New("mq message is already acknowledged")
+
+type mqMemoryBinding struct {
+	queue      string
+	routingKey string
+}
+
+type mqMemoryExchange struct {
+	kind     string
+	bindings []mqMemoryBinding
+}
+
+type mqMemoryQueue struct {
+	name     string
+	args     amqp.Table
+	messages []*Message
+	notify   chan struct{}
+}
+
+// mqMemoryBroker keep the queues and exchanges of NewMQMemory, every copy of the IMQ shares the broker
+type mqMemoryBroker struct {
+	mu        sync.Mutex
+	queues    map[string]*mqMemoryQueue
+	exchanges map[string]*mqMemoryExchange
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type mqMemory struct {
+	broker *mqMemoryBroker
+	ctx    context.Context
+}
+
+// NewMQMemory return an IMQ whose queues and exchanges are kept in process, e.g. for tests or a single binary in development.
+// It routes like RabbitMQ with the default, direct, fanout and topic exchanges, and dead letters the rejected and
+// expired messages with the x-dead-letter-exchange, x-dead-letter-routing-key and x-message-ttl arguments of the queue.
+// The messages are not persisted, and a message that is neither acknowledged nor rejected is dropped
+func NewMQMemory() IMQ {
+	return &mqMemory{
+		broker: &mqMemoryBroker{
+			queues:    map[string]*mqMemoryQueue{},
+			exchanges: map[string]*mqMemoryExchange{},
+			done:      make(chan struct{}),
+		},
+		ctx: context.Background(),
+	}
+}
+
+// WithContext return a copy of the mq whose published messages carry the trace of ctx and are recorded to its metrics
+func (m mqMemory) WithContext(ctx context.Context) IMQ {
+	m.ctx = ctx
+	return &m
+}
+
+func (m mqMemory) getContext() context.Context {
+	if m.ctx == nil {
+		return context.Background()
+	}
+
+	return m.ctx
+}
+
+// ReConnect is a no-op, the broker is in process
+func (m mqMemory) ReConnect() {}
+
+// Ping return ErrMQNotConnected once the broker is closed
+func (m mqMemory) Ping() error {
+	if m.broker.isClosed() {
+		return ErrMQNotConnected
+	}
+
+	return nil
+}
+
+// Close close the broker, the consumers return and the messages are dropped
+func (m mqMemory) Close() {
+	m.broker.closeOnce.Do(func() {
+		close(m.broker.done)
+	})
+}
+
+func (m mqMemory) PublishJSON(name string, data interface{}, options *MQPublishOptions) error {
+	return m.Publish(name, []byte(utils.JSONToString(data)), options)
+}
+
+// Publish publish body to the queue of name, the queue is declared first unless Exchange or NoDeclare is set.
+// A mandatory confirmed message that can't be routed returns ErrMQPublishReturned
+func (m mqMemory) Publish(name string, body []byte, options *MQPublishOptions) (err error) {
+	if options == nil {
+		options = &MQPublishOptions{}
+	}
+
+	ctx, span := Tracer().Start(m.getContext(), name+" publish", trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(
+		attribute.String("messaging.system", "memory"),
+		attribute.String("messaging.destination.name", name),
+	))
+	defer func() {
+		endSpan(span, err)
+		if metrics := MetricsFromContext(ctx); metrics != nil {
+			metrics.ObserveMQPublish(name, err)
+		}
+	}()
+
+	routingKey := name
+	if options.Exchange != "" {
+		if options.RoutingKey != "" {
+			routingKey = options.RoutingKey
+		}
+	} else if !options.NoDeclare {
+		if err := m.broker.declareQueue(name, options.Args); err != nil {
+			return err
+		}
+	}
+
+	messageID := options.MessageID
+	if messageID == "" {
+		messageID = utils.GetUUID()
+	}
+
+	contentType := options.ContentType
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+
+	routed, err := m.broker.publish(options.Exchange, routingKey, &Message{
+		ID:            messageID,
+		ContentType:   contentType,
+		CorrelationID: options.CorrelationID,
+		ReplyTo:       options.ReplyTo,
+		DeliveryMode:  options.DeliveryMode,
+		Headers:       injectMQHeaders(ctx, options.Headers),
+		Body:          append([]byte{}, body...),
+	})
+	if err != nil {
+		return err
+	}
+
+	if !routed && options.Mandatory && options.Confirm {
+		return ErrMQPublishReturned
+	}
+
+	return nil
+}
+
+// Request publish payload to the queue of name and wait for the reply of a ConsumeRequest handler up to timeout,
+// the queue of name is not declared and must exist. Default timeout is 30s
+func (m mqMemory) Request(name string, payload interface{}, timeout time.Duration) (body []byte, ierr IError) {
+	if timeout <= 0 {
+		timeout = defaultMQRequestTimeout
+	}
+
+	ctx, span := Tracer().Start(m.getContext(), name+" request", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("messaging.system", "memory"),
+		attribute.String("messaging.destination.name", name),
+	))
+	defer func() {
+		var err error
+		if ierr != nil {
+			err = ierr
+		}
+		endSpan(span, err)
+		if metrics := MetricsFromContext(ctx); metrics != nil {
+			metrics.ObserveMQPublish(name, err)
+		}
+	}()
+
+	replyTo := "amq.gen-" + utils.GetUUID()
+	if err := m.broker.declareQueue(replyTo, nil); err != nil {
+		return nil, newMQError(err, MQError)
+	}
+	defer m.broker.deleteQueue(replyTo)
+
+	correlationID := utils.GetUUID()
+	routed, err := m.broker.publish("", name, &Message{
+		ID:            utils.GetUUID(),
+		ContentType:   "application/json",
+		CorrelationID: correlationID,
+		ReplyTo:       replyTo,
+		Headers:       injectMQHeaders(ctx, nil),
+		Body:          []byte(utils.JSONToString(payload)),
+	})
+	if err != nil {
+		return nil, newMQError(err, MQError)
+	}
+	if !routed {
+		return nil, newMQError(fmt.Errorf("queue %s doesn't exist", name), mqRequestUnroutableError)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		reply, err := m.broker.pop(waitCtx, replyTo)
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return nil, newMQError(fmt.Errorf("no reply from %s in %v", name, timeout), mqRequestTimeoutError)
+		}
+		if err != nil {
+			return nil, newMQError(err, MQError)
+		}
+		if reply.CorrelationID != correlationID {
+			continue
+		}
+
+		return getMQReply(reply.Headers, reply.Body)
+	}
+}
+
+// DeclareExchange declare the exchange of name, the headers kind is not supported
+func (m mqMemory) DeclareExchange(name string, options *MQExchangeOptions) error {
+	if options == nil {
+		options = &MQExchangeOptions{}
+	}
+
+	kind := options.Kind
+	if kind == "" {
+		kind = amqp.ExchangeTopic
+	}
+
+	return m.broker.declareExchange(name, kind)
+}
+
+// BindQueue bind the queue to the exchange with routingKey, the queue and the exchange must be declared first
+func (m mqMemory) BindQueue(queue string, exchange string, routingKey string, _ *MQBindOptions) error {
+	return m.broker.bindQueue(queue, exchange, routingKey)
+}
+
+func (m mqMemory) Consume(ctx IMQContext, name string, onConsume func(message Message), options *MQConsumeOptions) {
+	m.ConsumeContext(ctx, name, func(_ IMQContext, message Message) {
+		onConsume(message)
+	}, options)
+}
+
+// ConsumeContext consume the messages of the queue like the RabbitMQ consumer, Prefetch is not used as every worker takes one message at a time.
+// It returns once the context of the consumer is done or the broker is closed, and the in-flight messages are handled
+func (m mqMemory) ConsumeContext(ctx IMQContext, name string, onConsume func(ctx IMQContext, message Message), options *MQConsumeOptions) {
+	if options == nil {
+		options = &MQConsumeOptions{}
+	}
+
+	if err := m.declareConsumer(name, options); err != nil {
+		ctx.NewError(err, MQError)
+		return
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	// the handlers are not cancelled by the shutdown of the consumer, they are waited for instead
+	baseCtx := context.WithoutCancel(ctx.GetContext())
+	workers := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				message, err := m.broker.pop(ctx.GetContext(), name)
+				if err != nil {
+					return
+				}
+
+				if !options.AutoAck {
+					message.acknowledger = &mqMemoryAcknowledger{broker: m.broker, queue: name, message: message}
+				}
+
+				handleMQMessage(ctx, baseCtx, "memory", name, *message, onConsume, options)
+			}
+		}()
+	}
+
+	workers.Wait()
+}
+
+// declareConsumer declare the queue of a consumer with its dead letter exchange and bindings
+func (m mqMemory) declareConsumer(name string, options *MQConsumeOptions) error {
+	args := options.Args
+	if options.DeadLetterExchange != "" {
+		if err := m.broker.declareExchange(options.DeadLetterExchange, amqp.ExchangeFanout); err != nil {
+			return err
+		}
+
+		if options.DeadLetterQueue != "" {
+			if err := m.broker.declareQueue(options.DeadLetterQueue, nil); err != nil {
+				return err
+			}
+			if err := m.broker.bindQueue(options.DeadLetterQueue, options.DeadLetterExchange, ""); err != nil {
+				return err
+			}
+		}
+
+		args = amqp.Table{}
+		for k, v := range options.Args {
+			args[k] = v
+		}
+		args["x-dead-letter-exchange"] = options.DeadLetterExchange
+	}
+
+	if err := m.broker.declareQueue(name, args); err != nil {
+		return err
+	}
+
+	for _, binding := range options.Bindings {
+		if err := m.broker.bindQueue(name, binding.Exchange, binding.RoutingKey); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *mqMemoryBroker) isClosed() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// declareQueue declare the queue of name, the arguments of the first declaration with arguments are kept
+func (b *mqMemoryBroker) declareQueue(name string, args amqp.Table) error {
+	if b.isClosed() {
+		return ErrMQNotConnected
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	q, ok := b.queues[name]
+	if !ok {
+		q = &mqMemoryQueue{name: name, notify: make(chan struct{})}
+		b.queues[name] = q
+	}
+
+	if len(q.args) == 0 && len(args) > 0 {
+		q.args = args
+	}
+
+	return nil
+}
+
+func (b *mqMemoryBroker) deleteQueue(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.queues, name)
+}
+
+func (b *mqMemoryBroker) declareExchange(name string, kind string) error {
+	if kind != amqp.ExchangeDirect && kind != amqp.ExchangeFanout && kind != amqp.ExchangeTopic {
+		return fmt.Errorf("exchange kind %s is not supported by the memory broker", kind)
+	}
+
+	if b.isClosed() {
+		return ErrMQNotConnected
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e, ok := b.exchanges[name]; ok {
+		if e.kind != kind {
+			return fmt.Errorf("exchange %s is declared as %s", name, e.kind)
+		}
+		return nil
+	}
+
+	b.exchanges[name] = &mqMemoryExchange{kind: kind}
+	return nil
+}
+
+func (b *mqMemoryBroker) bindQueue(queue string, exchange string, routingKey string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.queues[queue]; !ok {
+		return fmt.Errorf("queue %s doesn't exist", queue)
+	}
+
+	e, ok := b.exchanges[exchange]
+	if !ok {
+		return fmt.Errorf("exchange %s doesn't exist", exchange)
+	}
+
+	binding := mqMemoryBinding{queue: queue, routingKey: routingKey}
+	for _, existing := range e.bindings {
+		if existing == binding {
+			return nil
+		}
+	}
+
+	e.bindings = append(e.bindings, binding)
+	return nil
+}
+
+// publish route the message to the queues of the exchange, routed is false when no queue gets the message
+func (b *mqMemoryBroker) publish(exchange string, routingKey string, message *Message) (routed bool, err error) {
+	if b.isClosed() {
+		return false, ErrMQNotConnected
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if exchange != "" {
+		if _, ok := b.exchanges[exchange]; !ok {
+			return false, fmt.Errorf("exchange %s doesn't exist", exchange)
+		}
+	}
+
+	message.Exchange = exchange
+	message.RoutingKey = routingKey
+	message.Timestamp = time.Now()
+	return b.route(exchange, routingKey, message), nil
+}
+
+// route push a copy of the message to every matched queue, the lock must be held
+func (b *mqMemoryBroker) route(exchange string, routingKey string, message *Message) bool {
+	if exchange == "" {
+		q, ok := b.queues[routingKey]
+		if !ok {
+			return false
+		}
+
+		b.push(q, message)
+		return true
+	}
+
+	e, ok := b.exchanges[exchange]
+	if !ok {
+		return false
+	}
+
+	routed := map[string]bool{}
+	for _, binding := range e.bindings {
+		if routed[binding.queue] || !matchMQRoutingKey(e.kind, binding.routingKey, routingKey) {
+			continue
+		}
+
+		q, ok := b.queues[binding.queue]
+		if !ok {
+			continue
+		}
+
+		routed[binding.queue] = true
+		b.push(q, message)
+	}
+
+	return len(routed) > 0
+}
+
+// push add a copy of the message to the queue and wake up its consumers, the lock must be held
+func (b *mqMemoryBroker) push(q *mqMemoryQueue, message *Message) {
+	copied := *message
+	copied.Headers = map[string]interface{}{}
+	for k, v := range message.Headers {
+		copied.Headers[k] = v
+	}
+
+	q.messages = append(q.messages, &copied)
+	b.expire(q, &copied)
+	b.wake(q)
+}
+
+// expire dead letter the message once the x-message-ttl of the queue is passed and it's still in the queue
+func (b *mqMemoryBroker) expire(q *mqMemoryQueue, message *Message) {
+	ttl := getMQMemoryTTL(q.args)
+	if ttl <= 0 {
+		return
+	}
+
+	time.AfterFunc(ttl, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		for i, m := range q.messages {
+			if m == message {
+				q.messages = append(q.messages[:i], q.messages[i+1:]...)
+				b.deadLetter(q, message)
+				return
+			}
+		}
+	})
+}
+
+func (b *mqMemoryBroker) wake(q *mqMemoryQueue) {
+	close(q.notify)
+	q.notify = make(chan struct{})
+}
+
+// deadLetter route the message to the dead letter exchange of the queue, it's dropped when the queue has none.
+// The lock must be held
+func (b *mqMemoryBroker) deadLetter(q *mqMemoryQueue, message *Message) {
+	exchange, ok := q.args["x-dead-letter-exchange"].(string)
+	if !ok {
+		return
+	}
+
+	routingKey, ok := q.args["x-dead-letter-routing-key"].(string)
+	if !ok {
+		routingKey = message.RoutingKey
+	}
+
+	dead := *message
+	dead.Exchange = exchange
+	dead.RoutingKey = routingKey
+	dead.Redelivered = false
+	dead.acknowledger = nil
+	b.route(exchange, routingKey, &dead)
+}
+
+// pop take the first message of the queue, it waits for a message until ctx is done or the broker is closed
+func (b *mqMemoryBroker) pop(ctx context.Context, name string) (*Message, error) {
+	for {
+		b.mu.Lock()
+		q, ok := b.queues[name]
+		if !ok {
+			b.mu.Unlock()
+			return nil, fmt.Errorf("queue %s doesn't exist", name)
+		}
+
+		if len(q.messages) > 0 {
+			message := q.messages[0]
+			q.messages = q.messages[1:]
+			b.mu.Unlock()
+			return message, nil
+		}
+
+		notify := q.notify
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-b.done:
+			return nil, ErrMQNotConnected
+		case <-notify:
+		}
+	}
+}
+
+// mqMemoryAcknowledger requeue or dead letter a rejected message of the memory broker
+type mqMemoryAcknowledger struct {
+	broker  *mqMemoryBroker
+	queue   string
+	message *Message
+	mu      sync.Mutex
+	done    bool
+}
+
+func (a *mqMemoryAcknowledger) finish() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.done {
+		return false
+	}
+
+	a.done = true
+	return true
+}
+
+func (a *mqMemoryAcknowledger) Ack() error {
+	if !a.finish() {
+		return errMQMessageAcknowledged
+	}
+
+	return nil
+}
+
+func (a *mqMemoryAcknowledger) Nack(requeue bool) error {
+	if !a.finish() {
+		return errMQMessageAcknowledged
+	}
+
+	a.broker.mu.Lock()
+	defer a.broker.mu.Unlock()
+
+	q, ok := a.broker.queues[a.queue]
+	if !ok {
+		return nil
+	}
+
+	if !requeue {
+		a.broker.deadLetter(q, a.message)
+		return nil
+	}
+
+	redelivered := *a.message
+	redelivered.Redelivered = true
+	redelivered.acknowledger = nil
+	q.messages = append([]*Message{&redelivered}, q.messages...)
+	a.broker.wake(q)
+	return nil
+}
+
+// matchMQRoutingKey match the routing key of a message with the routing key of a binding
+func matchMQRoutingKey(kind string, pattern string, routingKey string) bool {
+	switch kind {
+	case amqp.ExchangeFanout:
+		return true
+	case amqp.ExchangeTopic:
+		return matchMQTopic(strings.Split(pattern, "."), strings.Split(routingKey, "."))
+	default:
+		return pattern == routingKey
+	}
+}
+
+// matchMQTopic match the words of a topic pattern, * matches a word and # matches zero or more words
+func matchMQTopic(pattern []string, words []string) bool {
+	if len(pattern) == 0 {
+		return len(words) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(words); i++ {
+			if matchMQTopic(pattern[1:], words[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(words) > 0 && matchMQTopic(pattern[1:], words[1:])
+	default:
+		return len(words) > 0 && pattern[0] == words[0] && matchMQTopic(pattern[1:], words[1:])
+	}
+}
+
+func getMQMemoryTTL(args amqp.Table) time.Duration {
+	switch v := args["x-message-ttl"].(type) {
+	case int:
+		return time.Duration(v) * time.Millisecond
+	case int32:
+		return time.Duration(v) * time.Millisecond
+	case int64:
+		return time.Duration(v) * time.Millisecond
+	default:
+		return 0
+	}
+}
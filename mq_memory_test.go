@@ -0,0 +1,201 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mqMemoryTestOrder struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount"`
+}
+
+func newMQMemoryTestContext(t *testing.T) *MQContext {
+	ctx := NewMQContext(&MQContextOptions{ContextOptions: &ContextOptions{ENV: NewEnv(), MQ: NewMQMemory()}}).(*MQContext)
+	t.Cleanup(func() {
+		ctx.stop()
+		ctx.consumers.Wait()
+		ctx.MQ().Close()
+	})
+
+	return ctx
+}
+
+func receiveMQMemoryTest[T any](t *testing.T, ch <-chan T) T {
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "no message is received")
+		var v T
+		return v
+	}
+}
+
+func TestMQMemory_ConsumeContext(t *testing.T) {
+	ctx := newMQMemoryTestContext(t)
+	require.NoError(t, ctx.MQ().DeclareExchange("orders", &MQExchangeOptions{Kind: "topic"}))
+
+	received := make(chan Message, 1)
+	ctx.ConsumeContext("orders.created", func(ctx IMQContext, message Message) {
+		received <- message
+		assert.NoError(t, message.Ack())
+	}, &MQConsumeOptions{Bindings: []MQBinding{{Exchange: "orders", RoutingKey: "order.*"}}})
+
+	require.Eventually(t, func() bool {
+		err := ctx.MQ().PublishJSON("orders", mqMemoryTestOrder{ID: "1", Amount: 10}, &MQPublishOptions{
+			Exchange:   "orders",
+			RoutingKey: "order.created",
+			MessageID:  "message-1",
+			Mandatory:  true,
+			Confirm:    true,
+		})
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	message := receiveMQMemoryTest(t, received)
+	assert.Equal(t, "message-1", message.ID)
+	assert.Equal(t, "orders", message.Exchange)
+	assert.Equal(t, "order.created", message.RoutingKey)
+	assert.JSONEq(t, `{"id":"1","amount":10}`, string(message.Body))
+
+	err := ctx.MQ().Publish("orders", []byte("{}"), &MQPublishOptions{Exchange: "orders", RoutingKey: "order.paid.late", Mandatory: true, Confirm: true})
+	assert.ErrorIs(t, err, ErrMQPublishReturned)
+}
+
+func TestMQMemory_ConsumeJSON(t *testing.T) {
+	ctx := newMQMemoryTestContext(t)
+
+	var attempts int32
+	ConsumeJSON(ctx, "payments", func(ctx IMQContext, data mqMemoryTestOrder) IError {
+		atomic.AddInt32(&attempts, 1)
+		return ctx.NewError(errors.New("payment gateway is down"), MQError)
+	}, &MQConsumeJSONOptions{MaxRetries: 2, RetryDelay: 10 * time.Millisecond})
+
+	dead := make(chan Message, 1)
+	ctx.Consume("payments.dead", func(message Message) {
+		dead <- message
+	}, &MQConsumeOptions{AutoAck: true})
+
+	require.Eventually(t, func() bool {
+		return ctx.MQ().PublishJSON("payments", mqMemoryTestOrder{ID: "2"}, &MQPublishOptions{}) == nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	message := receiveMQMemoryTest(t, dead)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	assert.Equal(t, 2, getMQRetryCount(message.Headers))
+	assert.Equal(t, "payments.dlx", message.Exchange)
+}
+
+func TestMQMemory_Request(t *testing.T) {
+	ctx := newMQMemoryTestContext(t)
+
+	ctx.ConsumeRequest("orders.get", func(ctx IMQContext, message Message) (interface{}, IError) {
+		order := mqMemoryTestOrder{}
+		if err := json.Unmarshal(message.Body, &order); err != nil {
+			return nil, ctx.NewError(err, mqMessageInvalidError)
+		}
+		if order.ID == "" {
+			return nil, Error{Status: http.StatusNotFound, Code: "NOT_FOUND", Message: "order is not found"}
+		}
+
+		order.Amount = 20
+		return order, nil
+	}, nil)
+
+	require.Eventually(t, func() bool {
+		_, ierr := ctx.MQ().Request("orders.get", mqMemoryTestOrder{ID: "3"}, time.Second)
+		return ierr == nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	order, ierr := RequestJSON[mqMemoryTestOrder](ctx.MQ(), "orders.get", mqMemoryTestOrder{ID: "3"}, time.Second)
+	require.NoError(t, ierr)
+	assert.Equal(t, mqMemoryTestOrder{ID: "3", Amount: 20}, *order)
+
+	_, ierr = ctx.MQ().Request("orders.get", mqMemoryTestOrder{}, time.Second)
+	require.Error(t, ierr)
+	assert.Equal(t, http.StatusNotFound, ierr.GetStatus())
+	assert.Equal(t, "NOT_FOUND", ierr.GetCode())
+
+	_, ierr = ctx.MQ().Request("orders.unknown", mqMemoryTestOrder{}, time.Second)
+	require.Error(t, ierr)
+	assert.Equal(t, mqRequestUnroutableError.Code, ierr.GetCode())
+}
+
+func TestMQMemory_StopDoesNotCancelHandlers(t *testing.T) {
+	ctx := newMQMemoryTestContext(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handlerErr := make(chan error, 1)
+	ctx.Consume("orders.created", func(message Message) {
+		close(started)
+		<-release
+
+		// the handler uses the context it closes over after the shutdown started
+		handlerErr <- ctx.GetContext().Err()
+		assert.NoError(t, message.Ack())
+	}, &MQConsumeOptions{})
+
+	require.Eventually(t, func() bool {
+		return ctx.MQ().PublishJSON("orders.created", mqMemoryTestOrder{ID: "1"}, nil) == nil
+	}, time.Second, 10*time.Millisecond)
+	receiveMQMemoryTest(t, started)
+
+	ctx.stop()
+	close(release)
+	assert.NoError(t, receiveMQMemoryTest(t, handlerErr))
+
+	assert.True(t, waitTimeout(ctx.consumers.Wait, 2*time.Second))
+}
+
+func TestMQMemory_PanicRequeue(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   *MQConsumeOptions
+		dead      bool
+		wantCalls int32
+	}{
+		{name: "requeued without dead letter exchange", options: &MQConsumeOptions{}, wantCalls: 2},
+		{name: "dead lettered", options: &MQConsumeOptions{DeadLetterExchange: "orders.dlx", DeadLetterQueue: "orders.dead"}, dead: true, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newMQMemoryTestContext(t)
+
+			var calls int32
+			received := make(chan Message, 1)
+			ctx.ConsumeContext("orders", func(_ IMQContext, message Message) {
+				if atomic.AddInt32(&calls, 1) == 1 {
+					panic("transient failure")
+				}
+				received <- message
+				assert.NoError(t, message.Ack())
+			}, tt.options)
+
+			if tt.dead {
+				ctx.ConsumeContext("orders.dead", func(_ IMQContext, message Message) {
+					received <- message
+					assert.NoError(t, message.Ack())
+				}, &MQConsumeOptions{})
+			}
+
+			require.Eventually(t, func() bool {
+				return ctx.MQ().Publish("orders", []byte("1"), &MQPublishOptions{MessageID: "message-1", Mandatory: true}) == nil
+			}, time.Second, 10*time.Millisecond)
+
+			message := receiveMQMemoryTest(t, received)
+			assert.Equal(t, "message-1", message.ID)
+			assert.Equal(t, !tt.dead, message.Redelivered)
+			assert.Equal(t, tt.wantCalls, atomic.LoadInt32(&calls))
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-errors/errors"
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// IMessageAcknowledger acknowledge or reject a message on its broker
+type IMessageAcknowledger interface {
+	Ack() error
+	Nack(requeue bool) error
+}
+
+// Message is a message consumed from any broker of IMQ
+type Message struct {
+	ID            string
+	Exchange      string
+	RoutingKey    string
+	ContentType   string
+	CorrelationID string
+	ReplyTo       string
+	DeliveryMode  uint8
+	Headers       map[string]interface{}
+	Body          []byte
+	Redelivered   bool
+	Timestamp     time.Time
+	acknowledger  IMessageAcknowledger
+	raw           interface{}
+}
+
+// Ack acknowledge the message, it's a no-op when the message is acknowledged automatically
+func (m Message) Ack() error {
+	if m.acknowledger == nil {
+		return nil
+	}
+
+	return m.acknowledger.Ack()
+}
+
+// Nack reject the message, it's delivered again when requeue is true, otherwise it's routed to the dead letter exchange of the queue
+func (m Message) Nack(requeue bool) error {
+	if m.acknowledger == nil {
+		return nil
+	}
+
+	return m.acknowledger.Nack(requeue)
+}
+
+// Raw return the message of the broker, it's amqp.Delivery for the AMQP broker
+func (m Message) Raw() interface{} {
+	return m.raw
+}
+
+type amqpAcknowledger struct {
+	delivery amqp.Delivery
+}
+
+func (a amqpAcknowledger) Ack() error {
+	return a.delivery.Ack(false)
+}
+
+func (a amqpAcknowledger) Nack(requeue bool) error {
+	return a.delivery.Nack(false, requeue)
+}
+
+func newAMQPMessage(d amqp.Delivery, autoAck bool) Message {
+	message := Message{
+		ID:            d.MessageId,
+		Exchange:      d.Exchange,
+		RoutingKey:    d.RoutingKey,
+		ContentType:   d.ContentType,
+		CorrelationID: d.CorrelationId,
+		ReplyTo:       d.ReplyTo,
+		DeliveryMode:  d.DeliveryMode,
+		Headers:       d.Headers,
+		Body:          d.Body,
+		Redelivered:   d.Redelivered,
+		Timestamp:     d.Timestamp,
+		raw:           d,
+	}
+
+	if !autoAck {
+		message.acknowledger = amqpAcknowledger{delivery: d}
+	}
+
+	return message
+}
+
+// handleMQMessage handle the message with a context that continues the trace of the publisher,
+// a panic of the handler is recovered and the message is rejected when it is not acknowledged automatically.
+// The rejected message goes to the dead letter exchange of the queue, it's requeued when the queue has none so it's not lost
+func handleMQMessage(ctx IMQContext, baseCtx context.Context, system string, name string, message Message, onConsume func(ctx IMQContext, message Message), options *MQConsumeOptions) {
+	if metrics := ctx.Metrics(); metrics != nil {
+		metrics.ObserveMQConsume(name)
+	}
+
+	msgCtx, span := Tracer().Start(extractMQHeaders(baseCtx, message.Headers), name+" process",
+		trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(
+			attribute.String("messaging.system", system),
+			attribute.String("messaging.destination.name", name),
+			attribute.String("messaging.message.id", message.ID),
+		))
+	handlerCtx := ctx.WithContext(msgCtx).(IMQContext)
+	defer span.End()
+
+	claim, complete, release := claimMQMessage(handlerCtx, name, &message, options.Dedup)
+	switch claim {
+	case MQDedupDone:
+		span.SetAttributes(attribute.Bool("messaging.duplicate", true))
+		if err := message.Ack(); err != nil {
+			handlerCtx.NewError(err, MQError)
+		}
+		return
+	case MQDedupProcessing:
+		// the handler of the claim may be gone e.g. its consumer crashed before the ack, so the message is kept until the claim expires
+		time.AfterFunc(getMQDedupRequeueDelay(options.Dedup), func() {
+			if err := message.Nack(true); err != nil {
+				handlerCtx.NewError(err, MQError)
+			}
+		})
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err := errors.New(fmt.Sprintf("%v", r))
+			handlerCtx.NewError(err, MQError)
+			span.RecordError(err)
+			if options.AutoAck {
+				release()
+			} else if err := message.Nack(!hasMQDeadLetter(options)); err != nil {
+				handlerCtx.NewError(err, MQError)
+			}
+		}
+	}()
+
+	onConsume(handlerCtx, message)
+	if options.AutoAck {
+		complete()
+	}
+}
+
+// hasMQDeadLetter return true when the rejected messages of the queue are routed to a dead letter exchange
+func hasMQDeadLetter(options *MQConsumeOptions) bool {
+	return options.DeadLetterExchange != "" || options.Args["x-dead-letter-exchange"] != nil
+}
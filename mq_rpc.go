@@ -0,0 +1,223 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Leakageonthelamp/go-leakage-core/utils"
+	"github.com/streadway/amqp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// MQHeaderError is the header of the reply that carries the error of the request handler
+	MQHeaderError = "x-error"
+
+	mqDirectReplyTo         = "amq.rabbitmq.reply-to"
+	defaultMQRequestTimeout = 30 * time.Second
+)
+
+var (
+	mqRequestTimeoutError = Error{
+		Status:  http.StatusGatewayTimeout,
+		Code:    "MQ_REQUEST_TIMEOUT",
+		Message: "mq request timed out"}
+
+	mqRequestUnroutableError = Error{
+		Status:  http.StatusServiceUnavailable,
+		Code:    "MQ_REQUEST_UNROUTABLE",
+		Message: "mq request has no queue to be routed to"}
+)
+
+// MQRequestHandler handle a request of Request, the returned data is published back as JSON and the error is returned to the requester
+type MQRequestHandler func(ctx IMQContext, message Message) (interface{}, IError)
+
+// mqReplyError is the error of the reply, Status is not in the JSON of Error
+type mqReplyError struct {
+	Status  int         `json:"status"`
+	Code    string      `json:"code"`
+	Message interface{} `json:"message"`
+	Fields  interface{} `json:"fields,omitempty"`
+}
+
+// newMQError return errorType with err as its original error
+func newMQError(err error, errorType Error) IError {
+	errorType.originalError = err
+	return errorType
+}
+
+// Request publish payload to the queue of name and wait for the reply of a ConsumeRequest handler up to timeout.
+// The reply is received on the direct reply-to queue, so the queue of name is not declared and must exist.
+// Default timeout is 30s
+func (m mq) Request(name string, payload interface{}, timeout time.Duration) (body []byte, ierr IError) {
+	if timeout <= 0 {
+		timeout = defaultMQRequestTimeout
+	}
+
+	ctx, span := Tracer().Start(m.getContext(), name+" request", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("messaging.system", "rabbitmq"),
+		attribute.String("messaging.destination.name", name),
+	))
+	defer func() {
+		var err error
+		if ierr != nil {
+			err = ierr
+		}
+		endSpan(span, err)
+		if metrics := MetricsFromContext(ctx); metrics != nil {
+			metrics.ObserveMQPublish(name, err)
+		}
+	}()
+
+	ch, err := m.channel(ctx)
+	if err != nil {
+		return nil, newMQError(err, MQError)
+	}
+	defer ch.Close()
+
+	// the reply-to queue must be consumed before the request is published
+	replies, err := ch.Consume(mqDirectReplyTo, "", true, false, false, false, nil)
+	if err != nil {
+		return nil, newMQError(err, MQError)
+	}
+	returns := ch.NotifyReturn(make(chan amqp.Return, 1))
+
+	correlationID := utils.GetUUID()
+	err = ch.Publish("", name, true, false, amqp.Publishing{
+		Headers:       injectMQHeaders(ctx, nil),
+		CorrelationId: correlationID,
+		ReplyTo:       mqDirectReplyTo,
+		ContentType:   "application/json",
+		Expiration:    strconv.FormatInt(timeout.Milliseconds(), 10),
+		Body:          []byte(utils.JSONToString(payload)),
+	})
+	if err != nil {
+		return nil, newMQError(err, MQError)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case reply, ok := <-replies:
+			if !ok {
+				return nil, newMQError(amqp.ErrClosed, MQError)
+			}
+			if reply.CorrelationId != correlationID {
+				continue
+			}
+
+			return getMQReply(reply.Headers, reply.Body)
+		case <-returns:
+			return nil, newMQError(fmt.Errorf("queue %s doesn't exist", name), mqRequestUnroutableError)
+		case <-timer.C:
+			return nil, newMQError(fmt.Errorf("no reply from %s in %v", name, timeout), mqRequestTimeoutError)
+		case <-ctx.Done():
+			return nil, newMQError(ctx.Err(), mqRequestTimeoutError)
+		}
+	}
+}
+
+// RequestJSON call Request and decode the reply to T
+func RequestJSON[T any](m IMQ, name string, payload interface{}, timeout time.Duration) (*T, IError) {
+	body, ierr := m.Request(name, payload, timeout)
+	if ierr != nil {
+		return nil, ierr
+	}
+
+	var data T
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, newMQError(err, MQError)
+	}
+
+	return &data, nil
+}
+
+// getMQReply return the body of the reply or the error of the request handler
+func getMQReply(headers map[string]interface{}, body []byte) ([]byte, IError) {
+	header, ok := headers[MQHeaderError].(string)
+	if !ok {
+		return body, nil
+	}
+
+	replyErr := &mqReplyError{}
+	if err := json.Unmarshal([]byte(header), replyErr); err != nil {
+		return nil, newMQError(err, MQError)
+	}
+
+	return nil, Error{
+		Status:        replyErr.Status,
+		Code:          replyErr.Code,
+		Message:       replyErr.Message,
+		Fields:        replyErr.Fields,
+		originalError: fmt.Errorf("%v", replyErr.Message),
+	}
+}
+
+// ConsumeRequest consume the requests of Request, the result of handler is published back to the requester.
+// The request is acknowledged after the reply is published when the consumer doesn't acknowledge automatically
+func (c *MQContext) ConsumeRequest(name string, handler MQRequestHandler, options *MQConsumeOptions) {
+	if options == nil {
+		options = &MQConsumeOptions{}
+	}
+
+	c.ConsumeContext(name, func(ctx IMQContext, message Message) {
+		replyMQRequest(ctx, message, handler)
+		if err := message.Ack(); err != nil {
+			ctx.NewError(err, MQError)
+		}
+	}, options)
+}
+
+func replyMQRequest(ctx IMQContext, message Message, handler MQRequestHandler) {
+	data, ierr := callMQRequestHandler(ctx, message, handler)
+	if message.ReplyTo == "" {
+		return
+	}
+
+	options := &MQPublishOptions{
+		NoDeclare:     true,
+		CorrelationID: message.CorrelationID,
+		ContentType:   "application/json",
+	}
+
+	var body []byte
+	if ierr != nil {
+		options.Headers = amqp.Table{MQHeaderError: utils.JSONToString(mqReplyError{
+			Status:  ierr.GetStatus(),
+			Code:    ierr.GetCode(),
+			Message: ierr.GetMessage(),
+			Fields:  getErrorFields(ierr),
+		})}
+	} else {
+		body = []byte(utils.JSONToString(data))
+	}
+
+	if err := ctx.MQ().Publish(message.ReplyTo, body, options); err != nil {
+		ctx.NewError(err, MQError)
+	}
+}
+
+// callMQRequestHandler call handler, a panic is returned as MQError
+func callMQRequestHandler(ctx IMQContext, message Message, handler MQRequestHandler) (data interface{}, ierr IError) {
+	defer func() {
+		if r := recover(); r != nil {
+			ierr = ctx.NewError(fmt.Errorf("%v", r), MQError)
+		}
+	}()
+
+	return handler(ctx, message)
+}
+
+func getErrorFields(ierr IError) interface{} {
+	if e, ok := ierr.(Error); ok {
+		return e.Fields
+	}
+
+	return nil
+}
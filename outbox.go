@@ -0,0 +1,298 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Leakageonthelamp/go-leakage-core/utils"
+	"github.com/streadway/amqp"
+	"gorm.io/gorm"
+)
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusSent    = "sent"
+	OutboxStatusFailed  = "failed"
+
+	defaultOutboxBatchSize    = 100
+	defaultOutboxMaxAttempts  = 10
+	defaultOutboxRetryBackoff = 5 * time.Second
+	defaultOutboxRetention    = 7 * 24 * time.Hour
+	maxOutboxRetryBackoff     = time.Hour
+)
+
+var outboxError = Error{
+	Status:  http.StatusInternalServerError,
+	Code:    "OUTBOX_ERROR",
+	Message: "outbox internal error"}
+
+// OutboxMessage is a message of the outbox table, the auto increment ID keeps the order of the messages
+type OutboxMessage struct {
+	ID           uint64     `json:"id" gorm:"primaryKey;autoIncrement"`
+	MessageID    string     `json:"message_id" gorm:"type:varchar(64);uniqueIndex"`
+	AggregateKey string     `json:"aggregate_key" gorm:"type:varchar(255);index"`
+	Queue        string     `json:"queue" gorm:"type:varchar(255)"`
+	Exchange     string     `json:"exchange" gorm:"type:varchar(255)"`
+	RoutingKey   string     `json:"routing_key" gorm:"type:varchar(255)"`
+	Durable      bool       `json:"durable"`
+	DeliveryMode uint8      `json:"delivery_mode"`
+	Headers      string     `json:"headers" gorm:"type:text"`
+	Payload      string     `json:"payload" gorm:"type:text"`
+	Status       string     `json:"status" gorm:"type:varchar(16);index:idx_outbox_status"`
+	Attempts     int        `json:"attempts"`
+	LastError    string     `json:"last_error" gorm:"type:text"`
+	AvailableAt  time.Time  `json:"available_at"`
+	SentAt       *time.Time `json:"sent_at" gorm:"index"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox"
+}
+
+// MigrateOutbox create or update the outbox table
+func MigrateOutbox(db *gorm.DB) error {
+	return db.AutoMigrate(&OutboxMessage{})
+}
+
+type OutboxPublishOptions struct {
+	AggregateKey string // the messages of the same key are published in the order they are added
+	MessageID    string // default is a new UUID
+	Exchange     string
+	RoutingKey   string
+	Durable      bool
+	DeliveryMode uint8
+	Headers      map[string]interface{}
+}
+
+type IOutbox interface {
+	PublishJSON(name string, data interface{}, options *OutboxPublishOptions) IError
+}
+
+type outbox struct {
+	ctx IContext
+}
+
+// NewOutbox return the outbox of ctx, the messages are written with ctx.DB(),
+// so they are committed with the transaction when ctx is the txCtx of IContext.Transaction
+func NewOutbox(ctx IContext) IOutbox {
+	return &outbox{ctx: ctx}
+}
+
+// PublishJSON add the message to the outbox, it's published to the queue of name by the relay
+func (o outbox) PublishJSON(name string, data interface{}, options *OutboxPublishOptions) IError {
+	if options == nil {
+		options = &OutboxPublishOptions{}
+	}
+
+	db := o.ctx.DB()
+	if db == nil {
+		return o.ctx.NewError(gorm.ErrInvalidDB, databaseError)
+	}
+
+	headers := ""
+	if len(options.Headers) > 0 {
+		headers = utils.JSONToString(options.Headers)
+	}
+
+	messageID := options.MessageID
+	if messageID == "" {
+		messageID = utils.GetUUID()
+	}
+
+	message := &OutboxMessage{
+		MessageID:    messageID,
+		AggregateKey: options.AggregateKey,
+		Queue:        name,
+		Exchange:     options.Exchange,
+		RoutingKey:   options.RoutingKey,
+		Durable:      options.Durable,
+		DeliveryMode: options.DeliveryMode,
+		Headers:      headers,
+		Payload:      utils.JSONToString(data),
+		Status:       OutboxStatusPending,
+		AvailableAt:  time.Now(),
+	}
+
+	if err := db.Create(message).Error; err != nil {
+		return o.ctx.NewError(err, databaseError)
+	}
+
+	return nil
+}
+
+type OutboxRelayOptions struct {
+	BatchSize    int           // number of messages of a run, default is 100
+	MaxAttempts  int           // the message is failed after the attempts, default is 10
+	RetryBackoff time.Duration // delay after the first failed attempt, it's doubled after every attempt up to 1h, default is 5s
+	Retention    time.Duration // the sent messages are deleted after the retention, default is 7 days and a negative value keeps them
+}
+
+type IOutboxRelay interface {
+	Run(ctx IContext) IError
+	Job() func(ctx ICronjobContext) error
+}
+
+type outboxRelay struct {
+	options *OutboxRelayOptions
+}
+
+// NewOutboxRelay return the relay that publishes the pending messages of the outbox with ctx.MQ().
+// The relay should run on a single instance, e.g. a singleton cronjob, so the order of an aggregate key is kept
+func NewOutboxRelay(options *OutboxRelayOptions) IOutboxRelay {
+	if options == nil {
+		options = &OutboxRelayOptions{}
+	}
+
+	relayOptions := *options
+	if relayOptions.BatchSize <= 0 {
+		relayOptions.BatchSize = defaultOutboxBatchSize
+	}
+	if relayOptions.MaxAttempts <= 0 {
+		relayOptions.MaxAttempts = defaultOutboxMaxAttempts
+	}
+	if relayOptions.RetryBackoff <= 0 {
+		relayOptions.RetryBackoff = defaultOutboxRetryBackoff
+	}
+	if relayOptions.Retention == 0 {
+		relayOptions.Retention = defaultOutboxRetention
+	}
+
+	return &outboxRelay{options: &relayOptions}
+}
+
+// Job return the handler of CronjobContext.AddJob that runs the relay
+func (r outboxRelay) Job() func(ctx ICronjobContext) error {
+	return func(ctx ICronjobContext) error {
+		if ierr := r.Run(ctx); ierr != nil {
+			return ierr
+		}
+
+		return nil
+	}
+}
+
+// Run publish a batch of the pending messages and delete the sent messages after the retention.
+// A message that is not published blocks the later messages of its aggregate key until it's sent or failed
+func (r outboxRelay) Run(ctx IContext) IError {
+	db := ctx.DB()
+	if db == nil {
+		return ctx.NewError(gorm.ErrInvalidDB, databaseError)
+	}
+
+	if ctx.MQ() == nil {
+		return ctx.NewError(ErrMQNotConnected, outboxError)
+	}
+
+	// the batch only has the due messages whose aggregate key has no earlier message waiting for a retry,
+	// so the messages in backoff don't starve the others
+	now := time.Now()
+	messages := make([]OutboxMessage, 0)
+	err := db.Where("status = ? AND available_at <= ?", OutboxStatusPending, now).
+		Where("aggregate_key = '' OR NOT EXISTS (?)", db.Session(&gorm.Session{NewDB: true}).Table("outbox AS earlier").Select("1").
+			Where("earlier.aggregate_key = outbox.aggregate_key AND earlier.status = ? AND earlier.id < outbox.id AND earlier.available_at > ?", OutboxStatusPending, now)).
+		Order("id").Limit(r.options.BatchSize).Find(&messages).Error
+	if err != nil {
+		return ctx.NewError(err, databaseError)
+	}
+
+	blocked := map[string]bool{}
+	for _, message := range messages {
+		if message.AggregateKey != "" && blocked[message.AggregateKey] {
+			continue
+		}
+
+		pending, ierr := r.publish(ctx, db, message)
+		if ierr != nil {
+			return ierr
+		}
+
+		if pending && message.AggregateKey != "" {
+			blocked[message.AggregateKey] = true
+		}
+	}
+
+	return r.cleanup(ctx, db)
+}
+
+// publish publish the message and record the result of the attempt, pending is true when the message is retried later
+func (r outboxRelay) publish(ctx IContext, db *gorm.DB, message OutboxMessage) (pending bool, ierr IError) {
+	headers := amqp.Table{}
+	if message.Headers != "" {
+		if err := json.Unmarshal([]byte(message.Headers), &headers); err != nil {
+			return r.fail(ctx, db, message, err, true)
+		}
+	}
+
+	err := ctx.MQ().Publish(message.Queue, []byte(message.Payload), &MQPublishOptions{
+		Exchange:     message.Exchange,
+		RoutingKey:   message.RoutingKey,
+		MessageID:    message.MessageID,
+		Durable:      message.Durable,
+		DeliveryMode: message.DeliveryMode,
+		Headers:      headers,
+		ContentType:  "application/json",
+		Confirm:      true,
+	})
+	if err != nil {
+		return r.fail(ctx, db, message, err, false)
+	}
+
+	err = db.Model(&OutboxMessage{}).Where("id = ?", message.ID).Updates(map[string]interface{}{
+		"status":     OutboxStatusSent,
+		"attempts":   message.Attempts + 1,
+		"sent_at":    time.Now(),
+		"last_error": "",
+	}).Error
+	if err != nil {
+		return false, ctx.NewError(err, databaseError)
+	}
+
+	return false, nil
+}
+
+// fail record the failed attempt, the message is retried with exponential backoff until MaxAttempts
+func (r outboxRelay) fail(ctx IContext, db *gorm.DB, message OutboxMessage, cause error, permanent bool) (pending bool, ierr IError) {
+	attempts := message.Attempts + 1
+	status := OutboxStatusPending
+	if permanent || attempts >= r.options.MaxAttempts {
+		status = OutboxStatusFailed
+		ctx.NewError(fmt.Errorf("outbox message %s is failed after %d attempts: %w", message.MessageID, attempts, cause), outboxError)
+	}
+
+	backoff := r.options.RetryBackoff
+	for i := 1; i < attempts && backoff < maxOutboxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxOutboxRetryBackoff {
+		backoff = maxOutboxRetryBackoff
+	}
+
+	err := db.Model(&OutboxMessage{}).Where("id = ?", message.ID).Updates(map[string]interface{}{
+		"status":       status,
+		"attempts":     attempts,
+		"last_error":   cause.Error(),
+		"available_at": time.Now().Add(backoff),
+	}).Error
+	if err != nil {
+		return false, ctx.NewError(err, databaseError)
+	}
+
+	return status == OutboxStatusPending, nil
+}
+
+func (r outboxRelay) cleanup(ctx IContext, db *gorm.DB) IError {
+	if r.options.Retention < 0 {
+		return nil
+	}
+
+	err := db.Where("status = ? AND sent_at < ?", OutboxStatusSent, time.Now().Add(-r.options.Retention)).Delete(&OutboxMessage{}).Error
+	if err != nil {
+		return ctx.NewError(err, databaseError)
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// outboxTestMQ record the published message IDs, the messages of fail are not published
+type outboxTestMQ struct {
+	IMQ
+	mu        sync.Mutex
+	published []string
+	fail      map[string]bool
+}
+
+func (m *outboxTestMQ) WithContext(_ context.Context) IMQ {
+	return m
+}
+
+func (m *outboxTestMQ) Publish(_ string, _ []byte, options *MQPublishOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.fail[options.MessageID] {
+		return errors.New("broker is down")
+	}
+
+	m.published = append(m.published, options.MessageID)
+	return nil
+}
+
+func (m *outboxTestMQ) setFail(messageID string, fail bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.fail[messageID] = fail
+}
+
+func (m *outboxTestMQ) takePublished() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	published := m.published
+	m.published = nil
+	return published
+}
+
+func newOutboxTestContext(t *testing.T) (IContext, *outboxTestMQ) {
+	db := newTestSQLite(t)
+	require.NoError(t, MigrateOutbox(db))
+
+	mq := &outboxTestMQ{fail: map[string]bool{}}
+	return NewContext(&ContextOptions{ENV: NewEnv(), DB: db, MQ: mq}), mq
+}
+
+func addOutboxTestMessage(t *testing.T, ctx IContext, messageID string, aggregateKey string) {
+	ierr := NewOutbox(ctx).PublishJSON("orders", map[string]string{"id": messageID}, &OutboxPublishOptions{
+		MessageID:    messageID,
+		AggregateKey: aggregateKey,
+	})
+	require.NoError(t, ierr)
+}
+
+func getOutboxTestMessage(t *testing.T, ctx IContext, messageID string) *OutboxMessage {
+	message := &OutboxMessage{}
+	require.NoError(t, ctx.DB().Where("message_id = ?", messageID).Take(message).Error)
+	return message
+}
+
+// makeOutboxTestMessageDue end the backoff of the message
+func makeOutboxTestMessageDue(t *testing.T, ctx IContext, messageID string) {
+	err := ctx.DB().Model(&OutboxMessage{}).Where("message_id = ?", messageID).Update("available_at", time.Now().Add(-time.Second)).Error
+	require.NoError(t, err)
+}
+
+func TestOutboxRelay_AggregateKeyOrder(t *testing.T) {
+	ctx, mq := newOutboxTestContext(t)
+	relay := NewOutboxRelay(nil)
+
+	addOutboxTestMessage(t, ctx, "a1", "a")
+	addOutboxTestMessage(t, ctx, "a2", "a")
+	addOutboxTestMessage(t, ctx, "b1", "b")
+	addOutboxTestMessage(t, ctx, "c1", "")
+	mq.setFail("a1", true)
+
+	// a2 waits for a1, the other keys are not blocked
+	require.NoError(t, relay.Run(ctx))
+	assert.Equal(t, []string{"b1", "c1"}, mq.takePublished())
+	assert.Equal(t, OutboxStatusPending, getOutboxTestMessage(t, ctx, "a2").Status)
+
+	// a1 is in backoff, so a2 is still not published
+	addOutboxTestMessage(t, ctx, "b2", "b")
+	require.NoError(t, relay.Run(ctx))
+	assert.Equal(t, []string{"b2"}, mq.takePublished())
+
+	mq.setFail("a1", false)
+	makeOutboxTestMessageDue(t, ctx, "a1")
+	require.NoError(t, relay.Run(ctx))
+	assert.Equal(t, []string{"a1", "a2"}, mq.takePublished())
+	assert.Equal(t, OutboxStatusSent, getOutboxTestMessage(t, ctx, "a2").Status)
+}
+
+func TestOutboxRelay_BackoffDoesNotStarve(t *testing.T) {
+	ctx, mq := newOutboxTestContext(t)
+	relay := NewOutboxRelay(&OutboxRelayOptions{BatchSize: 2})
+
+	for _, id := range []string{"a1", "b1"} {
+		addOutboxTestMessage(t, ctx, id, id)
+		mq.setFail(id, true)
+	}
+	require.NoError(t, relay.Run(ctx))
+	assert.Empty(t, mq.takePublished())
+
+	// the batch is not filled by the messages in backoff
+	addOutboxTestMessage(t, ctx, "c1", "c")
+	addOutboxTestMessage(t, ctx, "d1", "")
+	require.NoError(t, relay.Run(ctx))
+	assert.Equal(t, []string{"c1", "d1"}, mq.takePublished())
+}
+
+func TestOutboxRelay_Backoff(t *testing.T) {
+	ctx, mq := newOutboxTestContext(t)
+	relay := NewOutboxRelay(&OutboxRelayOptions{MaxAttempts: 3, RetryBackoff: time.Minute})
+
+	addOutboxTestMessage(t, ctx, "a1", "a")
+	mq.setFail("a1", true)
+
+	for attempt, backoff := range []time.Duration{time.Minute, 2 * time.Minute} {
+		start := time.Now()
+		require.NoError(t, relay.Run(ctx))
+
+		message := getOutboxTestMessage(t, ctx, "a1")
+		assert.Equal(t, OutboxStatusPending, message.Status)
+		assert.Equal(t, attempt+1, message.Attempts)
+		assert.Equal(t, "broker is down", message.LastError)
+		assert.WithinDuration(t, start.Add(backoff), message.AvailableAt, 5*time.Second)
+
+		// the message is not retried before its backoff
+		require.NoError(t, relay.Run(ctx))
+		assert.Equal(t, attempt+1, getOutboxTestMessage(t, ctx, "a1").Attempts)
+
+		makeOutboxTestMessageDue(t, ctx, "a1")
+	}
+
+	// the last attempt fails the message, it doesn't block its aggregate key anymore
+	addOutboxTestMessage(t, ctx, "a2", "a")
+	require.NoError(t, relay.Run(ctx))
+	message := getOutboxTestMessage(t, ctx, "a1")
+	assert.Equal(t, OutboxStatusFailed, message.Status)
+	assert.Equal(t, 3, message.Attempts)
+	assert.Equal(t, []string{"a2"}, mq.takePublished())
+}
+
+func TestOutboxRelay_Retention(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention time.Duration
+		want      []string
+	}{
+		{name: "deleted after the retention", retention: time.Hour, want: []string{"recent"}},
+		{name: "kept with a negative retention", retention: -1, want: []string{"old", "recent"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := newOutboxTestContext(t)
+			relay := NewOutboxRelay(&OutboxRelayOptions{Retention: tt.retention})
+
+			for id, sentAt := range map[string]time.Time{"old": time.Now().Add(-2 * time.Hour), "recent": time.Now().Add(-time.Minute)} {
+				addOutboxTestMessage(t, ctx, id, "")
+				err := ctx.DB().Model(&OutboxMessage{}).Where("message_id = ?", id).Updates(map[string]interface{}{
+					"status":  OutboxStatusSent,
+					"sent_at": sentAt,
+				}).Error
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, relay.Run(ctx))
+
+			ids := make([]string, 0)
+			require.NoError(t, ctx.DB().Model(&OutboxMessage{}).Order("message_id").Pluck("message_id", &ids).Error)
+			assert.Equal(t, tt.want, ids)
+		})
+	}
+}
@@ -22,6 +22,7 @@ type IRepository[M IModel] interface {
 	Delete(conds ...any) core.IError                                                       // Function to delete a value that matches the given conditions
 	HardDelete(conds ...any) core.IError                                                   // Function to hard delete a value that matches the given conditions
 	Pagination(pageOptions *models.PageOptions) (*Pagination[M], core.IError)              // Function to perform pagination on the records
+	CursorPagination(pageOptions *models.PageOptions) (*CursorPagination[M], core.IError)  // Function to perform keyset (cursor) pagination on the records
 	Save(values any) core.IError                                                           // Function to set values on a model
 	Where(query any, args ...any) IRepository[M]                                           // Function to filter records based on a query
 	Preload(query string, args ...any) IRepository[M]                                      // Function to preload associations
@@ -67,6 +68,8 @@ func NewWithDB[M IModel](ctx core.IContext, db *gorm.DB) IRepository[M] {
 	newDB := db
 	if newDB == nil {
 		newDB = ctx.DB()
+	} else {
+		newDB = newDB.WithContext(ctx.GetContext())
 	}
 	return &BaseRepository[M]{ctx: ctx, db: newDB.Model(item)}
 }
@@ -172,6 +175,28 @@ func (m *BaseRepository[M]) Pagination(pageOptions *models.PageOptions) (*Pagina
 	}, nil
 }
 
+// CursorPagination paginate records by the cursor of the page options, the cursor is built from the OrderBy columns
+func (m *BaseRepository[M]) CursorPagination(pageOptions *models.PageOptions) (*CursorPagination[M], core.IError) {
+	list := make([]M, 0)
+	pageRes, err := core.PaginateCursor(m.getDBInstance(), &list, pageOptions)
+	if errors.Is(err, core.ErrInvalidCursor) {
+		return nil, m.ctx.NewError(err, errmsgs.BadRequest)
+	}
+
+	if err != nil {
+		return nil, m.ctx.NewError(err, errmsgs.DBError)
+	}
+
+	return &CursorPagination[M]{
+		Limit:      pageRes.Limit,
+		Count:      pageRes.Count,
+		Total:      pageRes.Total,
+		NextCursor: pageRes.NextCursor,
+		PrevCursor: pageRes.PrevCursor,
+		Items:      list,
+	}, nil
+}
+
 func (m *BaseRepository[M]) Save(values any) core.IError {
 	model := new(M)
 	err := m.getDBInstance().Model(model).Save(values).Error
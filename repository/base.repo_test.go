@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	core "github.com/Leakageonthelamp/go-leakage-core"
+	"github.com/Leakageonthelamp/go-leakage-core/errmsgs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type testUser struct {
+	ID   uint   `gorm:"primaryKey"`
+	Name string `gorm:"index"`
+	Age  int
+}
+
+func (testUser) TableName() string {
+	return "users"
+}
+
+type testOrder struct {
+	ID     uint `gorm:"primaryKey"`
+	UserID uint
+}
+
+func (testOrder) TableName() string {
+	return "orders"
+}
+
+var errTestRollback = core.Error{Status: http.StatusConflict, Code: "ROLLBACK", Message: "rollback"}
+
+func newTestContext(t *testing.T) core.IContext {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+	})
+
+	require.NoError(t, db.AutoMigrate(&testUser{}, &testOrder{}))
+
+	return core.NewContext(&core.ContextOptions{ENV: core.NewEnv(), DB: db})
+}
+
+func countTestRows[M IModel](t *testing.T, ctx core.IContext) int64 {
+	count, ierr := New[M](ctx).Count()
+	require.NoError(t, ierr)
+	return count
+}
+
+// createInTransaction create a user and its order with two repositories of txCtx,
+// the rows are visible to txCtx but not outside of the transaction yet
+func createInTransaction(t *testing.T, ctx core.IContext, txCtx core.IContext, name string) {
+	user := &testUser{Name: name}
+	require.NoError(t, New[testUser](txCtx).Create(user))
+	require.NoError(t, New[testOrder](txCtx).Create(&testOrder{UserID: user.ID}))
+
+	found, ierr := New[testUser](txCtx).FindOne("name = ?", name)
+	require.NoError(t, ierr)
+	assert.Equal(t, user.ID, found.ID)
+
+	_, ierr = New[testUser](ctx).FindOne("name = ?", name)
+	require.Error(t, ierr)
+	assert.Equal(t, errmsgs.NotFound.Code, ierr.GetCode())
+}
+
+func TestContextTransaction_Commit(t *testing.T) {
+	ctx := newTestContext(t)
+
+	ierr := ctx.Transaction(func(txCtx core.IContext) error {
+		createInTransaction(t, ctx, txCtx, "john")
+		return nil
+	})
+	require.NoError(t, ierr)
+
+	assert.Equal(t, int64(1), countTestRows[testUser](t, ctx))
+	assert.Equal(t, int64(1), countTestRows[testOrder](t, ctx))
+}
+
+func TestContextTransaction_Rollback(t *testing.T) {
+	ctx := newTestContext(t)
+
+	ierr := ctx.Transaction(func(txCtx core.IContext) error {
+		createInTransaction(t, ctx, txCtx, "john")
+		return errTestRollback
+	})
+	require.Error(t, ierr)
+	assert.Equal(t, errTestRollback.Code, ierr.GetCode())
+
+	ierr = ctx.Transaction(func(txCtx core.IContext) error {
+		createInTransaction(t, ctx, txCtx, "jane")
+		return errors.New("payment failed")
+	})
+	require.Error(t, ierr)
+	assert.Equal(t, http.StatusInternalServerError, ierr.GetStatus())
+
+	assert.Equal(t, int64(0), countTestRows[testUser](t, ctx))
+	assert.Equal(t, int64(0), countTestRows[testOrder](t, ctx))
+}
+
+func TestContextTransaction_Panic(t *testing.T) {
+	ctx := newTestContext(t)
+
+	assert.Panics(t, func() {
+		_ = ctx.Transaction(func(txCtx core.IContext) error {
+			createInTransaction(t, ctx, txCtx, "john")
+			panic("payment gateway is down")
+		})
+	})
+
+	assert.Equal(t, int64(0), countTestRows[testUser](t, ctx))
+	assert.Equal(t, int64(0), countTestRows[testOrder](t, ctx))
+}
+
+func TestContextTransaction_Nested(t *testing.T) {
+	ctx := newTestContext(t)
+
+	ierr := ctx.Transaction(func(txCtx core.IContext) error {
+		createInTransaction(t, ctx, txCtx, "john")
+
+		// the savepoint of the nested call is rolled back without the outer transaction
+		ierr := txCtx.Transaction(func(nestedCtx core.IContext) error {
+			createInTransaction(t, ctx, nestedCtx, "jane")
+			assert.Equal(t, int64(2), countTestRows[testUser](t, txCtx))
+			return errTestRollback
+		})
+		assert.Error(t, ierr)
+		assert.Equal(t, int64(1), countTestRows[testUser](t, txCtx))
+
+		return txCtx.Transaction(func(nestedCtx core.IContext) error {
+			createInTransaction(t, ctx, nestedCtx, "jim")
+			return nil
+		})
+	})
+	require.NoError(t, ierr)
+
+	users, ierr := New[testUser](ctx).Order("id").FindAll()
+	require.NoError(t, ierr)
+	require.Len(t, users, 2)
+	assert.Equal(t, "john", users[0].Name)
+	assert.Equal(t, "jim", users[1].Name)
+	assert.Equal(t, int64(2), countTestRows[testOrder](t, ctx))
+}
@@ -60,6 +60,11 @@ func (m *MockRepository[M]) Pagination(pageOptions *models.PageOptions) (*Pagina
 	return args.Get(0).(*Pagination[M]), core.MockIError(args, 1)
 }
 
+func (m *MockRepository[M]) CursorPagination(pageOptions *models.PageOptions) (*CursorPagination[M], core.IError) {
+	args := m.Called(pageOptions)
+	return args.Get(0).(*CursorPagination[M]), core.MockIError(args, 1)
+}
+
 func (m *MockRepository[M]) Save(values interface{}) core.IError {
 	args := m.Called(values)
 	return core.MockIError(args, 0)
@@ -1,13 +1,26 @@
 package repository
 
 type Pagination[M any] struct {
-	Page  int64 `json:"page" example:"1"`
-	Total int64 `json:"total" example:"45"`
-	Limit int64 `json:"limit" example:"30"`
-	Count int64 `json:"count" example:"30"`
-	Items []M   `json:"items"`
+	Page  int64  `json:"page" example:"1"`
+	Total *int64 `json:"total,omitempty" example:"45"`
+	Limit int64  `json:"limit" example:"30"`
+	Count int64  `json:"count" example:"30"`
+	Items []M    `json:"items"`
+}
+
+type CursorPagination[M any] struct {
+	Limit      int64  `json:"limit" example:"30"`
+	Count      int64  `json:"count" example:"30"`
+	Total      *int64 `json:"total,omitempty" example:"45"`
+	NextCursor string `json:"next_cursor" example:"eyJkIjoibmV4dCIsInYiOlsxXX0"`
+	PrevCursor string `json:"prev_cursor" example:""`
+	Items      []M    `json:"items"`
 }
 
 type IModel interface {
 	TableName() string
 }
+
+type IMongoModel interface {
+	CollectionName() string
+}
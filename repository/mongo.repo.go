@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"errors"
+
+	core "github.com/Leakageonthelamp/go-leakage-core"
+	"github.com/Leakageonthelamp/go-leakage-core/errmsgs"
+	"github.com/Leakageonthelamp/go-leakage-core/models"
+	"github.com/Leakageonthelamp/go-leakage-core/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const MongoDeletedAtField = "deleted_at"
+
+type IMongoRepository[M IMongoModel] interface {
+	Find(filter any, opts ...*options.FindOptions) ([]M, core.IError)                                                               // Function to find all documents that match the given filter
+	FindOne(filter any, opts ...*options.FindOneOptions) (*M, core.IError)                                                          // Function to find the first document that matches the given filter
+	Count(filter any, opts ...*options.CountOptions) (int64, core.IError)                                                           // Function to count the documents that match the given filter
+	Pagination(filter any, pageOptions *models.PageOptions, opts ...*options.FindOptions) (*Pagination[M], core.IError)             // Function to perform pagination on the documents
+	CursorPagination(filter any, pageOptions *models.PageOptions, opts ...*options.FindOptions) (*CursorPagination[M], core.IError) // Function to perform keyset (cursor) pagination on the documents
+	Insert(item *M, opts ...*options.InsertOneOptions) core.IError                                                                  // Function to insert a document into the collection
+	Update(filter any, update any, opts ...*options.UpdateOptions) core.IError                                                      // Function to update the first document that matches the given filter
+	SoftDelete(filter any) core.IError                                                                                              // Function to mark the documents that match the given filter as deleted
+	HardDelete(filter any, opts ...*options.DeleteOptions) core.IError                                                              // Function to remove the documents that match the given filter
+	Unscoped() IMongoRepository[M]                                                                                                  // Function to include soft deleted documents in the queries
+}
+
+type MongoRepository[M IMongoModel] struct {
+	ctx      core.IContext
+	db       core.IMongoDB
+	coll     string
+	unscoped bool
+}
+
+func NewMongo[M IMongoModel](ctx core.IContext) IMongoRepository[M] {
+	item := new(M)
+	return &MongoRepository[M]{ctx: ctx, db: ctx.DBMongo(), coll: (*item).CollectionName()}
+}
+
+func NewMongoWithDB[M IMongoModel](ctx core.IContext, db core.IMongoDB) IMongoRepository[M] {
+	item := new(M)
+	newDB := db
+	if newDB == nil {
+		newDB = ctx.DBMongo()
+	} else {
+		newDB = newDB.WithContext(ctx.GetContext())
+	}
+	return &MongoRepository[M]{ctx: ctx, db: newDB, coll: (*item).CollectionName()}
+}
+
+// Find find documents that match given filter, soft deleted documents are excluded unless Unscoped
+func (m *MongoRepository[M]) Find(filter any, opts ...*options.FindOptions) ([]M, core.IError) {
+	list := make([]M, 0)
+	err := m.db.Find(&list, m.coll, m.getFilter(filter), opts...)
+	if err != nil {
+		return nil, m.ctx.NewError(err, errmsgs.DBError)
+	}
+
+	return list, nil
+}
+
+// FindOne find first document that match given filter
+func (m *MongoRepository[M]) FindOne(filter any, opts ...*options.FindOneOptions) (*M, core.IError) {
+	item := new(M)
+	err := m.db.FindOne(item, m.coll, m.getFilter(filter), opts...)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, m.ctx.NewError(err, errmsgs.NotFound)
+	}
+
+	if err != nil {
+		return nil, m.ctx.NewError(err, errmsgs.DBError)
+	}
+
+	return item, nil
+}
+
+func (m *MongoRepository[M]) Count(filter any, opts ...*options.CountOptions) (int64, core.IError) {
+	count, err := m.db.Count(m.coll, m.getFilter(filter), opts...)
+	if err != nil {
+		return 0, m.ctx.NewError(err, errmsgs.DBError)
+	}
+
+	return count, nil
+}
+
+func (m *MongoRepository[M]) Pagination(filter any, pageOptions *models.PageOptions, opts ...*options.FindOptions) (*Pagination[M], core.IError) {
+	list := make([]M, 0)
+	pageRes, err := m.db.FindPagination(&list, m.coll, m.getFilter(filter), pageOptions, opts...)
+	if err != nil {
+		return nil, m.ctx.NewError(err, errmsgs.DBError)
+	}
+
+	return &Pagination[M]{
+		Limit: pageRes.Limit,
+		Page:  pageRes.Page,
+		Total: pageRes.Total,
+		Count: pageRes.Count,
+		Items: list,
+	}, nil
+}
+
+// CursorPagination paginate documents by the cursor of the page options, the cursor is built from the OrderBy fields
+func (m *MongoRepository[M]) CursorPagination(filter any, pageOptions *models.PageOptions, opts ...*options.FindOptions) (*CursorPagination[M], core.IError) {
+	list := make([]M, 0)
+	pageRes, err := m.db.FindCursorPagination(&list, m.coll, m.getFilter(filter), pageOptions, opts...)
+	if errors.Is(err, core.ErrInvalidCursor) {
+		return nil, m.ctx.NewError(err, errmsgs.BadRequest)
+	}
+
+	if err != nil {
+		return nil, m.ctx.NewError(err, errmsgs.DBError)
+	}
+
+	return &CursorPagination[M]{
+		Limit:      pageRes.Limit,
+		Count:      pageRes.Count,
+		Total:      pageRes.Total,
+		NextCursor: pageRes.NextCursor,
+		PrevCursor: pageRes.PrevCursor,
+		Items:      list,
+	}, nil
+}
+
+// Insert insert the document into the collection
+func (m *MongoRepository[M]) Insert(item *M, opts ...*options.InsertOneOptions) core.IError {
+	_, err := m.db.Create(m.coll, item, opts...)
+	if err != nil {
+		return m.ctx.NewError(err, errmsgs.DBError)
+	}
+
+	return nil
+}
+
+// Update update first document that match given filter, update must be an update document e.g. bson.M{"$set": ...}
+func (m *MongoRepository[M]) Update(filter any, update any, opts ...*options.UpdateOptions) core.IError {
+	res, err := m.db.UpdateOne(m.coll, m.getFilter(filter), update, opts...)
+	if err != nil {
+		return m.ctx.NewError(err, errmsgs.DBError)
+	}
+
+	if res.MatchedCount == 0 && res.UpsertedCount == 0 {
+		return m.ctx.NewError(mongo.ErrNoDocuments, errmsgs.NotFound)
+	}
+
+	return nil
+}
+
+// SoftDelete set deleted_at of documents that match given filter
+func (m *MongoRepository[M]) SoftDelete(filter any) core.IError {
+	_, err := m.db.UpdateMany(m.coll, m.getFilter(filter), bson.M{
+		"$set": bson.M{MongoDeletedAtField: utils.GetCurrentDateTime()},
+	})
+	if err != nil {
+		return m.ctx.NewError(err, errmsgs.DBError)
+	}
+
+	return nil
+}
+
+// HardDelete remove documents that match given filter from the collection
+func (m *MongoRepository[M]) HardDelete(filter any, opts ...*options.DeleteOptions) core.IError {
+	_, err := m.db.DeleteMany(m.coll, m.getFilter(filter), opts...)
+	if err != nil {
+		return m.ctx.NewError(err, errmsgs.DBError)
+	}
+
+	return nil
+}
+
+func (m *MongoRepository[M]) Unscoped() IMongoRepository[M] {
+	m.unscoped = true
+	return m
+}
+
+func (m *MongoRepository[M]) getFilter(filter any) any {
+	if filter == nil {
+		filter = bson.M{}
+	}
+
+	if m.unscoped {
+		return filter
+	}
+
+	return bson.M{"$and": bson.A{filter, bson.M{MongoDeletedAtField: nil}}}
+}
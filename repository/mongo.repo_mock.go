@@ -0,0 +1,99 @@
+package repository
+
+import (
+	core "github.com/Leakageonthelamp/go-leakage-core"
+	"github.com/Leakageonthelamp/go-leakage-core/models"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MockMongoRepository is a mock of IMongoRepository interface.
+type MockMongoRepository[M IMongoModel] struct {
+	mock.Mock
+}
+
+func NewMongoMock[M IMongoModel]() *MockMongoRepository[M] {
+	return &MockMongoRepository[M]{}
+}
+
+func (m *MockMongoRepository[M]) Find(filter interface{}, opts ...*options.FindOptions) ([]M, core.IError) {
+	varargs := []interface{}{filter}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	args := m.Called(varargs...)
+	return args.Get(0).([]M), core.MockIError(args, 1)
+}
+
+func (m *MockMongoRepository[M]) FindOne(filter interface{}, opts ...*options.FindOneOptions) (*M, core.IError) {
+	varargs := []interface{}{filter}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	args := m.Called(varargs...)
+	return args.Get(0).(*M), core.MockIError(args, 1)
+}
+
+func (m *MockMongoRepository[M]) Count(filter interface{}, opts ...*options.CountOptions) (int64, core.IError) {
+	varargs := []interface{}{filter}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	args := m.Called(varargs...)
+	return args.Get(0).(int64), core.MockIError(args, 1)
+}
+
+func (m *MockMongoRepository[M]) Pagination(filter interface{}, pageOptions *models.PageOptions, opts ...*options.FindOptions) (*Pagination[M], core.IError) {
+	varargs := []interface{}{filter, pageOptions}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	args := m.Called(varargs...)
+	return args.Get(0).(*Pagination[M]), core.MockIError(args, 1)
+}
+
+func (m *MockMongoRepository[M]) CursorPagination(filter interface{}, pageOptions *models.PageOptions, opts ...*options.FindOptions) (*CursorPagination[M], core.IError) {
+	varargs := []interface{}{filter, pageOptions}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	args := m.Called(varargs...)
+	return args.Get(0).(*CursorPagination[M]), core.MockIError(args, 1)
+}
+
+func (m *MockMongoRepository[M]) Insert(item *M, opts ...*options.InsertOneOptions) core.IError {
+	varargs := []interface{}{item}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	args := m.Called(varargs...)
+	return core.MockIError(args, 0)
+}
+
+func (m *MockMongoRepository[M]) Update(filter interface{}, update interface{}, opts ...*options.UpdateOptions) core.IError {
+	varargs := []interface{}{filter, update}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	args := m.Called(varargs...)
+	return core.MockIError(args, 0)
+}
+
+func (m *MockMongoRepository[M]) SoftDelete(filter interface{}) core.IError {
+	args := m.Called(filter)
+	return core.MockIError(args, 0)
+}
+
+func (m *MockMongoRepository[M]) HardDelete(filter interface{}, opts ...*options.DeleteOptions) core.IError {
+	varargs := []interface{}{filter}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	args := m.Called(varargs...)
+	return core.MockIError(args, 0)
+}
+
+func (m *MockMongoRepository[M]) Unscoped() IMongoRepository[M] {
+	m.Called()
+	return m
+}
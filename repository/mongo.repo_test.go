@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	core "github.com/Leakageonthelamp/go-leakage-core"
+	"github.com/Leakageonthelamp/go-leakage-core/errmsgs"
+	"github.com/Leakageonthelamp/go-leakage-core/models"
+	"github.com/Leakageonthelamp/go-leakage-core/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type testMongoUser struct {
+	ID   string `bson:"_id"`
+	Name string `bson:"name"`
+}
+
+func (testMongoUser) CollectionName() string {
+	return "users"
+}
+
+// testMongoDB record the pagination calls and decode items to dest like the mongo cursor does
+type testMongoDB struct {
+	core.IMongoDB
+	coll   string
+	filter interface{}
+	items  []testMongoUser
+	total  int64
+	err    error
+}
+
+func (db *testMongoDB) WithContext(_ context.Context) core.IMongoDB {
+	return db
+}
+
+func (db *testMongoDB) FindPagination(dest interface{}, coll string, filter interface{}, pageOptions *models.PageOptions, _ ...*options.FindOptions) (*models.PageResponse, error) {
+	db.coll = coll
+	db.filter = filter
+	if db.err != nil {
+		return nil, db.err
+	}
+
+	reflect.ValueOf(dest).Elem().Set(reflect.ValueOf(db.items))
+	return &models.PageResponse{Total: &db.total, Limit: pageOptions.Limit, Page: pageOptions.Page, Count: int64(len(db.items))}, nil
+}
+
+func (db *testMongoDB) FindCursorPagination(_ interface{}, coll string, filter interface{}, _ *models.PageOptions, _ ...*options.FindOptions) (*models.CursorPageResponse, error) {
+	db.coll = coll
+	db.filter = filter
+	return nil, db.err
+}
+
+func newTestMongoRepository(db *testMongoDB) IMongoRepository[testMongoUser] {
+	ctx := core.NewContext(&core.ContextOptions{ENV: core.NewEnv()})
+	return NewMongoWithDB[testMongoUser](ctx, db)
+}
+
+func TestMongoRepository_Pagination(t *testing.T) {
+	filter := bson.M{"name": "john"}
+	items := []testMongoUser{{ID: "u1", Name: "john"}, {ID: "u2", Name: "john"}}
+
+	tests := []struct {
+		name       string
+		unscoped   bool
+		err        error
+		wantFilter interface{}
+		wantCode   string
+	}{
+		{
+			name:       "soft deleted documents are excluded",
+			wantFilter: bson.M{"$and": bson.A{filter, bson.M{MongoDeletedAtField: nil}}},
+		},
+		{
+			name:       "unscoped",
+			unscoped:   true,
+			wantFilter: filter,
+		},
+		{
+			name:       "database error",
+			err:        errors.New("connection refused"),
+			wantFilter: bson.M{"$and": bson.A{filter, bson.M{MongoDeletedAtField: nil}}},
+			wantCode:   errmsgs.DBError.Code,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &testMongoDB{items: items, total: 5, err: tt.err}
+			repo := newTestMongoRepository(db)
+			if tt.unscoped {
+				repo = repo.Unscoped()
+			}
+
+			page, ierr := repo.Pagination(filter, &models.PageOptions{Page: 2, Limit: 2})
+			assert.Equal(t, "users", db.coll)
+			assert.Equal(t, tt.wantFilter, db.filter)
+			if tt.wantCode != "" {
+				require.Error(t, ierr)
+				assert.Equal(t, tt.wantCode, ierr.GetCode())
+				return
+			}
+
+			require.NoError(t, ierr)
+			assert.Equal(t, &Pagination[testMongoUser]{Page: 2, Total: utils.ToPointer(int64(5)), Limit: 2, Count: 2, Items: items}, page)
+		})
+	}
+}
+
+func TestMongoRepository_CursorPaginationInvalidCursor(t *testing.T) {
+	repo := newTestMongoRepository(&testMongoDB{err: core.ErrInvalidCursor})
+
+	_, ierr := repo.CursorPagination(nil, &models.PageOptions{Cursor: "invalid"})
+	require.Error(t, ierr)
+	assert.Equal(t, errmsgs.BadRequest.Code, ierr.GetCode())
+}
@@ -21,6 +21,11 @@ import (
 	"github.com/gojektech/heimdall/v6/httpclient"
 	"github.com/gojektech/heimdall/v6/plugins"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type RequesterOptions struct {
@@ -149,13 +154,13 @@ func newRequesterWithOptions(ctx IContext, options *RequesterOptions) *Requester
 
 func (r Requester) Get(url string, options *RequesterOptions) (*RequestResponse, error) {
 	url, headers := r.getOptions(url, options)
-	res, err := r.client.Get(url, headers)
+	res, err := r.do(http.MethodGet, url, nil, headers)
 	return r.transformResponse(res, err)
 }
 
 func (r Requester) Delete(url string, options *RequesterOptions) (*RequestResponse, error) {
 	url, headers := r.getOptions(url, options)
-	res, err := r.client.Delete(url, headers)
+	res, err := r.do(http.MethodDelete, url, nil, headers)
 	return r.transformResponse(res, err)
 }
 
@@ -170,7 +175,7 @@ func (r Requester) Post(url string, body interface{}, options *RequesterOptions)
 
 		headers.Add("Content-Type", contentType)
 
-		res, err := r.client.Post(url, newBody, headers)
+		res, err := r.do(http.MethodPost, url, newBody, headers)
 		return r.transformResponse(res, err)
 
 	} else if options.IsURLEncode {
@@ -182,10 +187,10 @@ func (r Requester) Post(url string, body interface{}, options *RequesterOptions)
 		headers.Add("Content-Type", "application/x-www-form-urlencoded")
 		headers.Add("Content-Length", length)
 
-		res, err := r.client.Post(url, newBody, headers)
+		res, err := r.do(http.MethodPost, url, newBody, headers)
 		return r.transformResponse(res, err)
 	} else {
-		res, err := r.client.Post(url, r.getJSONBody(body, options), headers)
+		res, err := r.do(http.MethodPost, url, r.getJSONBody(body, options), headers)
 		return r.transformResponse(res, err)
 
 	}
@@ -199,24 +204,57 @@ func (r Requester) Create(method consts.RequesterMethodType, url string, body in
 		newBody = r.getJSONBody(body, options)
 	}
 
-	req, err := http.NewRequest(string(method), url, newBody)
-	req.Header = headers
-	res, err := r.client.Do(req)
+	res, err := r.do(string(method), url, newBody, headers)
 	return r.transformResponse(res, err)
 }
 
 func (r Requester) Put(url string, body interface{}, options *RequesterOptions) (*RequestResponse, error) {
 	url, headers := r.getOptions(url, options)
-	res, err := r.client.Put(url, r.getJSONBody(body, options), headers)
+	res, err := r.do(http.MethodPut, url, r.getJSONBody(body, options), headers)
 	return r.transformResponse(res, err)
 }
 
 func (r Requester) Patch(url string, body interface{}, options *RequesterOptions) (*RequestResponse, error) {
 	url, headers := r.getOptions(url, options)
-	res, err := r.client.Patch(url, r.getJSONBody(body, options), headers)
+	res, err := r.do(http.MethodPatch, url, r.getJSONBody(body, options), headers)
 	return r.transformResponse(res, err)
 }
 
+// do send the request bound to the context of the requester, so it's cancelled together with the context
+func (r Requester) do(method string, url string, body io.Reader, headers http.Header) (*http.Response, error) {
+	ctx, span := Tracer().Start(r.ctx.GetContext(), "HTTP "+method, trace.WithSpanKind(trace.SpanKindClient))
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+
+	if headers == nil {
+		headers = http.Header{}
+	}
+
+	// the headers of the caller are copied before the traceparent header is added
+	req.Header = headers.Clone()
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	span.SetAttributes(
+		attribute.String("http.request.method", method),
+		attribute.String("url.full", req.URL.Redacted()),
+		attribute.String("server.address", req.URL.Host),
+	)
+
+	res, err := r.client.Do(req)
+	if err == nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+		if res.StatusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+		}
+	}
+
+	endSpan(span, err)
+	return res, err
+}
+
 func (r Requester) transformResponse(res *http.Response, err error) (*RequestResponse, error) {
 	var data map[string]interface{}
 
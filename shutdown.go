@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const defaultShutdownTimeout = 10 * time.Second
+
+// NotifyShutdown return a context that is done when the process receives SIGINT or SIGTERM
+func NotifyShutdown() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// getShutdownTimeout return SHUTDOWN_TIMEOUT, it's the time given to the in-flight work once the shutdown begins
+func getShutdownTimeout(env IENV) time.Duration {
+	if env == nil || env.Config().ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+
+	return env.Config().ShutdownTimeout
+}
+
+// getShutdownDelay return SHUTDOWN_DELAY, it's the time the HTTP server keeps serving once it's not ready anymore,
+// so the load balancers see the readiness go down before the connections are refused
+func getShutdownDelay(env IENV) time.Duration {
+	if env == nil {
+		return 0
+	}
+
+	return env.Config().ShutdownDelay
+}
+
+// waitTimeout wait until wait returns or timeout is reached, it returns false on timeout
+func waitTimeout(wait func(), timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// CloseContextOptions close every database, cache and MQ connection of options
+func CloseContextOptions(options *ContextOptions) {
+	if options == nil {
+		return
+	}
+
+	if options.MQ != nil {
+		options.MQ.Close()
+	}
+
+	if options.Cache != nil {
+		options.Cache.Close()
+	}
+
+	for _, cache := range options.Caches {
+		cache.Close()
+	}
+
+	if options.MongoDB != nil {
+		options.MongoDB.Close()
+	}
+
+	for _, db := range options.MongoDBS {
+		db.Close()
+	}
+
+	if options.DB != nil {
+		closeSQL(options.DB)
+	}
+
+	for _, db := range options.DBS {
+		closeSQL(db)
+	}
+}
+
+func closeSQL(db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		NewLoggerSimple().Error(err)
+		return
+	}
+
+	if err := sqlDB.Close(); err != nil {
+		NewLoggerSimple().Error(err)
+	}
+}
+
+// shutdownHealthServer stop the health server that is started by startHealthServer
+func shutdownHealthServer(server *http.Server) {
+	if server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		NewLoggerSimple().Error(err)
+	}
+}
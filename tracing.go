@@ -0,0 +1,152 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+
+	tracerName = "github.com/Leakageonthelamp/go-leakage-core"
+)
+
+type Tracing struct {
+	ServiceName  string
+	Exporter     string                // stdout or otlp, tracing is disabled when empty
+	Endpoint     string                // host:port of the OTLP HTTP collector, default is localhost:4318
+	Insecure     bool                  // send to the collector without TLS
+	SampleRatio  float64               // ratio of the traces that are sampled, default is 1
+	SpanExporter sdktrace.SpanExporter // custom exporter, it is used instead of Exporter
+}
+
+func NewTracing(env *ENVConfig) *Tracing {
+	return &Tracing{
+		ServiceName: env.Service,
+		Exporter:    env.TraceExporter,
+		Endpoint:    env.TraceEndpoint,
+		Insecure:    env.TraceInsecure,
+		SampleRatio: 1,
+	}
+}
+
+// Start set the global tracer provider and the W3C trace context propagator, the returned function flushes and stops
+// the exporter and must be called before the service exits
+func (t *Tracing) Start() (func(ctx context.Context) error, error) {
+	exporter := t.SpanExporter
+	if exporter == nil {
+		var err error
+		switch t.Exporter {
+		case "":
+			return func(ctx context.Context) error { return nil }, nil
+		case TracingExporterStdout:
+			exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+		case TracingExporterOTLP:
+			opts := make([]otlptracehttp.Option, 0)
+			if t.Endpoint != "" {
+				opts = append(opts, otlptracehttp.WithEndpoint(t.Endpoint))
+			}
+			if t.Insecure {
+				opts = append(opts, otlptracehttp.WithInsecure())
+			}
+			exporter, err = otlptracehttp.New(context.Background(), opts...)
+		default:
+			err = fmt.Errorf("unsupported trace exporter %q", t.Exporter)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(t.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := t.SampleRatio
+	if ratio <= 0 {
+		ratio = 1
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+// Tracer return the tracer of the library, it's a no-op tracer until Tracing.Start is called
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// endSpan end the span and record err to the span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// mqHeaderCarrier adapt the headers of a message to propagation.TextMapCarrier
+type mqHeaderCarrier map[string]interface{}
+
+func (c mqHeaderCarrier) Get(key string) string {
+	v, ok := c[key].(string)
+	if !ok {
+		return ""
+	}
+
+	return v
+}
+
+func (c mqHeaderCarrier) Set(key string, value string) {
+	c[key] = value
+}
+
+func (c mqHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// injectMQHeaders return headers with the trace context of ctx, headers is copied so the options of the caller are not changed
+func injectMQHeaders(ctx context.Context, headers map[string]interface{}) map[string]interface{} {
+	newHeaders := map[string]interface{}{}
+	for k, v := range headers {
+		newHeaders[k] = v
+	}
+
+	otel.GetTextMapPropagator().Inject(ctx, mqHeaderCarrier(newHeaders))
+	return newHeaders
+}
+
+// extractMQHeaders return ctx with the trace context of headers
+func extractMQHeaders(ctx context.Context, headers map[string]interface{}) context.Context {
+	if headers == nil {
+		return ctx
+	}
+
+	return otel.GetTextMapPropagator().Extract(ctx, mqHeaderCarrier(headers))
+}
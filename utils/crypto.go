@@ -339,7 +339,7 @@ func SignMessageWithOption(privateKey interface{}, message string, option *SignM
 		if !ok {
 			return "", errors.New("incorrect private key type")
 		}
-		sig, err := rsaPrivateKey.Sign(rand.Reader, hash(StringToBytes(message)), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		sig, err := rsaPrivateKey.Sign(rand.Reader, hash(StringToBytes(message)), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256})
 		if err != nil {
 			return "", err
 		}
@@ -349,7 +349,7 @@ func SignMessageWithOption(privateKey interface{}, message string, option *SignM
 		if !ok {
 			return "", errors.New("incorrect private key type")
 		}
-		sig, err := rsaPrivateKey.Sign(rand.Reader, hash384(StringToBytes(message)), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		sig, err := rsaPrivateKey.Sign(rand.Reader, hash384(StringToBytes(message)), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA384})
 		if err != nil {
 			return "", err
 		}
@@ -359,7 +359,7 @@ func SignMessageWithOption(privateKey interface{}, message string, option *SignM
 		if !ok {
 			return "", errors.New("incorrect private key type")
 		}
-		sig, err := rsaPrivateKey.Sign(rand.Reader, hash512(StringToBytes(message)), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		sig, err := rsaPrivateKey.Sign(rand.Reader, hash512(StringToBytes(message)), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA512})
 		if err != nil {
 			return "", err
 		}
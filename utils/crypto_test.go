@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignMessageWithOption(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm x509.SignatureAlgorithm
+	}{
+		{name: "ecdsa sha256", algorithm: x509.ECDSAWithSHA256},
+		{name: "ecdsa sha384", algorithm: x509.ECDSAWithSHA384},
+		{name: "ecdsa sha512", algorithm: x509.ECDSAWithSHA512},
+		{name: "rsa sha256", algorithm: x509.SHA256WithRSA},
+		{name: "rsa-pss sha256", algorithm: x509.SHA256WithRSAPSS},
+		{name: "rsa-pss sha384", algorithm: x509.SHA384WithRSAPSS},
+		{name: "rsa-pss sha512", algorithm: x509.SHA512WithRSAPSS},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyPair, err := GenerateKeyPairWithOption(&GenerateKeyPairOption{Algorithm: tt.algorithm})
+			require.NoError(t, err)
+
+			var privateKey interface{}
+			var publicKey string
+			switch k := keyPair.(type) {
+			case *KeyPair:
+				privateKey, publicKey = k.PrivateKey, k.PublicKeyPem
+			case *RSAKeyPair:
+				privateKey, publicKey = k.PrivateKey, k.PublicKeyPem
+			}
+
+			signature, err := SignMessageWithOption(privateKey, "message", &SignMessageOption{Algorithm: tt.algorithm})
+			require.NoError(t, err)
+
+			option := &VerifySignatureOption{Algorithm: tt.algorithm}
+			valid, err := VerifySignatureWithOption(publicKey, signature, "message", option)
+			require.NoError(t, err)
+			assert.True(t, valid)
+
+			valid, _ = VerifySignatureWithOption(publicKey, signature, "tampered", option)
+			assert.False(t, valid)
+		})
+	}
+}
+
+func TestSignMessageWithOption_PSSHash(t *testing.T) {
+	tests := []struct {
+		algorithm x509.SignatureAlgorithm
+		hash      crypto.Hash
+		sum       func(b []byte) []byte
+	}{
+		{algorithm: x509.SHA256WithRSAPSS, hash: crypto.SHA256, sum: hash},
+		{algorithm: x509.SHA384WithRSAPSS, hash: crypto.SHA384, sum: hash384},
+		{algorithm: x509.SHA512WithRSAPSS, hash: crypto.SHA512, sum: hash512},
+	}
+
+	keyPair, err := GenerateKeyPairWithOption(&GenerateKeyPairOption{Algorithm: x509.SHA256WithRSAPSS})
+	require.NoError(t, err)
+	privateKey := keyPair.(*RSAKeyPair).PrivateKey
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm.String(), func(t *testing.T) {
+			signature, err := SignMessageWithOption(privateKey, "message", &SignMessageOption{Algorithm: tt.algorithm})
+			require.NoError(t, err)
+
+			sig, err := base64.StdEncoding.DecodeString(signature)
+			require.NoError(t, err)
+
+			// the signature is verifiable by any RSA-PSS implementation with the hash of the algorithm
+			err = rsa.VerifyPSS(&privateKey.PublicKey, tt.hash, tt.sum([]byte("message")), sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+			assert.NoError(t, err)
+		})
+	}
+}